@@ -3,10 +3,14 @@ package astdiff
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"log"
+	"path"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/bootun/veronica/parser"
@@ -19,28 +23,83 @@ const (
 	ChangeTypeAdded    ChangeType = "added"    // 新增
 	ChangeTypeRemoved  ChangeType = "removed"  // 移除
 	ChangeTypeModified ChangeType = "modified" // 修改
+	ChangeTypeMoved    ChangeType = "moved"    // 内容未变化，移动到了同一个包中的其他文件
+	ChangeTypeRenamed  ChangeType = "renamed"  // 除名称外内容未变化，被重命名
 )
 
+// ChangeDetail 描述修改的具体种类
+type ChangeDetail string
+
+const (
+	DetailSignature  ChangeDetail = "signature"  // 函数的参数、返回值或类型参数，变量和常量声明的类型发生变化，或声明的种类发生变化
+	DetailReceiver   ChangeDetail = "receiver"   // 只有方法的接收器发生变化，如接收器的变量名
+	DetailBody       ChangeDetail = "body"       // 只有函数体发生变化
+	DetailLayout     ChangeDetail = "layout"     // 类型的定义发生变化，如接口的方法集、结构体的嵌入字段或字段的顺序
+	DetailFields     ChangeDetail = "fields"     // 只有结构体的非嵌入字段被添加、删除或修改了类型和标签，每个字段的变化会单独报告
+	DetailValue      ChangeDetail = "value"      // 只有变量或常量的初始值发生变化
+	DetailDependency ChangeDetail = "dependency" // 声明本身没有变化，但其引用的外部模块的版本发生了变化
+)
+
+// Breaking 判断该种类的修改是否可能破坏依赖方的编译或行为
+func (d ChangeDetail) Breaking() bool {
+	return d == DetailSignature || d == DetailLayout || d == DetailFields
+}
+
 type Change struct {
-	Type       ChangeType // "added", "removed", "modified"
+	Type       ChangeType // "added", "removed", "modified", "moved", "renamed"
 	Package    string
 	Object     string // 函数名、变量名等
-	ObjectType string // "func", "var", "const", "type"
+	ObjectType string // "func", "var", "const", "type", "field"
 	ObjectID   string // 对象的唯一标识符
 	File       string // 文件名
+	OldFile    string // 声明所在的文件发生变化时，旧版本中的文件名
+	// Detail 是修改的具体种类，只在修改时存在
+	Detail ChangeDetail
+	// 以下字段只在重命名时存在
+	OldObject   string // 旧版本中的名称
+	OldObjectID string // 旧版本中的唯一标识符
 }
 
 type AnalysisResult struct {
 	Changes []Change
-	Objects map[string]struct {
-		Type     string
-		Package  string
-		Position token.Position
-		Node     ast.Node
+	// Objects 是所有的顶层声明，LoadDiff 返回的结果中为新版本中的顶层声明
+	Objects map[string]Object
+}
+
+// Object 是一个顶层声明
+type Object struct {
+	Type     string
+	Package  string
+	Position token.Position
+	Node     ast.Node
+	// Imports 是声明所在文件的导入, key: 包在文件中的名称, value: 包路径
+	Imports map[string]string
+}
+
+// Mode 决定如何比较两个版本中的声明
+type Mode string
+
+const (
+	// ModeStrict 忽略注释和代码格式，但代码的任何写法变化都视为修改
+	ModeStrict Mode = "strict"
+	// ModeSemantic 在 ModeStrict 的基础上，还会忽略括号、同一个包的不同导入别名
+	// 以及同一个字面量的不同写法(如 0x10 与 16)，只报告可能改变程序行为的修改
+	ModeSemantic Mode = "semantic"
+)
+
+// ParseMode 解析配置文件中的比较模式，为空时使用 ModeStrict
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(mode) {
+	case "", ModeStrict:
+		return ModeStrict, nil
+	case ModeSemantic:
+		return ModeSemantic, nil
+	default:
+		return "", fmt.Errorf("invalid diff mode: %s", mode)
 	}
 }
 
-func LoadDiff(oldPkgs, newPkgs []*packages.Package) (*AnalysisResult, error) {
+func LoadDiff(oldPkgs, newPkgs []*packages.Package, mode Mode) (*AnalysisResult, error) {
 	// 分析两个版本
 	oldResult, err := analyzeCommit(oldPkgs)
 	if err != nil {
@@ -51,19 +110,40 @@ func LoadDiff(oldPkgs, newPkgs []*packages.Package) (*AnalysisResult, error) {
 		log.Fatalf("Failed to analyze new commit: %v", err)
 	}
 	// 比较结果并输出
-	result := compareResults(oldResult, newResult)
+	result := compareResults(oldResult, newResult, mode)
 	return result, nil
 }
 
+// MarkModified 将 objects 中 ids 对应的顶层声明标记为修改，用于声明本身未变化，
+// 但其依赖的外部模块(如 go.mod 中的版本)发生变化的场景。
+// objects 通常是 LoadDiff 返回的新版本中的顶层声明
+func MarkModified(objects map[string]Object, ids []string) []Change {
+	changes := make([]Change, 0, len(ids))
+	for _, id := range ids {
+		obj, ok := objects[id]
+		if !ok {
+			continue
+		}
+		parts := strings.Split(id, ":")
+		objName := parts[len(parts)-1]
+		baseFileName := filepath.Base(obj.Position.Filename)
+		changes = append(changes, Change{
+			Type:       ChangeTypeModified,
+			Package:    obj.Package,
+			Object:     objName,
+			ObjectType: obj.Type,
+			ObjectID:   id,
+			File:       fmt.Sprintf("%s/%s", obj.Package, baseFileName),
+			Detail:     DetailDependency,
+		})
+	}
+	return changes
+}
+
 func analyzeCommit(pkgs []*packages.Package) (*AnalysisResult, error) {
 	// 分析包中的顶层定义
 	result := &AnalysisResult{
-		Objects: make(map[string]struct {
-			Type     string
-			Package  string
-			Position token.Position
-			Node     ast.Node
-		}),
+		Objects: make(map[string]Object),
 	}
 	for _, pkg := range pkgs {
 		analyzePackage(pkg, result)
@@ -77,22 +157,19 @@ func analyzePackage(pkg *packages.Package, result *AnalysisResult) {
 	for _, file := range pkg.Syntax {
 		fullFileName := pkg.Fset.File(file.Pos()).Name()
 		baseFileName := filepath.Base(fullFileName)
+		imports := fileImports(file)
 
 		for _, decl := range file.Decls {
 			switch d := decl.(type) {
 			case *ast.FuncDecl:
 				funcName := parser.GetFuncOrMethodName(d)
 				id := parser.GetObjectID(pkgName, baseFileName, funcName)
-				result.Objects[id] = struct {
-					Type     string
-					Package  string
-					Position token.Position
-					Node     ast.Node
-				}{
+				result.Objects[id] = Object{
 					Type:     "func",
 					Package:  pkgName,
 					Position: pkg.Fset.Position(d.Pos()),
 					Node:     d,
+					Imports:  imports,
 				}
 			case *ast.GenDecl:
 				// 处理顶层声明 (var, const, type)
@@ -107,30 +184,22 @@ func analyzePackage(pkg *packages.Package, result *AnalysisResult) {
 								objType = "var"
 							}
 							id := parser.GetObjectID(pkgName, baseFileName, name.Name)
-							result.Objects[id] = struct {
-								Type     string
-								Package  string
-								Position token.Position
-								Node     ast.Node
-							}{
+							result.Objects[id] = Object{
 								Type:     objType,
 								Package:  pkgName,
 								Position: pkg.Fset.Position(name.Pos()),
 								Node:     s,
+								Imports:  imports,
 							}
 						}
 					case *ast.TypeSpec: // type
 						id := parser.GetObjectID(pkgName, baseFileName, s.Name.Name)
-						result.Objects[id] = struct {
-							Type     string
-							Package  string
-							Position token.Position
-							Node     ast.Node
-						}{
+						result.Objects[id] = Object{
 							Type:     "type",
 							Package:  pkgName,
 							Position: pkg.Fset.Position(s.Pos()),
 							Node:     s,
+							Imports:  imports,
 						}
 					case *ast.ImportSpec:
 						continue
@@ -143,15 +212,12 @@ func analyzePackage(pkg *packages.Package, result *AnalysisResult) {
 	}
 }
 
-func compareResults(old, new *AnalysisResult) *AnalysisResult {
+func compareResults(old, new *AnalysisResult, mode Mode) *AnalysisResult {
 	result := &AnalysisResult{
-		Objects: make(map[string]struct {
-			Type     string
-			Package  string
-			Position token.Position
-			Node     ast.Node
-		}),
+		Objects: new.Objects,
 	}
+	// 结构体中发生变化的字段，字段不参与重命名的检测
+	var fieldChanges []Change
 
 	// 检查新增和修改的对象
 	for key, newObj := range new.Objects {
@@ -161,30 +227,29 @@ func compareResults(old, new *AnalysisResult) *AnalysisResult {
 		objName := parts[len(parts)-1]
 		fileName := fmt.Sprintf("%s/%s", newObj.Package, baseFileName)
 		if oldObj, exists := old.Objects[key]; exists {
-			// 检查包名和类型是否变化
-			if oldObj.Package != newObj.Package || oldObj.Type != newObj.Type {
-				// unreachable code
-				panic(fmt.Sprintf("package or type changed: %s, %s", oldObj.Package, newObj.Package))
-				// result.Changes = append(result.Changes, Change{
-				// 	Type:       ChangeTypeModified,
-				// 	Package:    newObj.Package,
-				// 	Object:     objName,
-				// 	ObjectType: newObj.Type,
-				// 	ObjectID:   key,
-				// 	File:       fileName,
-				// })
-			} else {
-				// 检查对象内容是否变化
-				if !astNodesEqual(oldObj.Node, newObj.Node) {
-					result.Changes = append(result.Changes, Change{
-						Type:       ChangeTypeModified,
-						Package:    newObj.Package,
-						Object:     objName,
-						ObjectType: newObj.Type,
-						ObjectID:   key,
-						File:       fileName,
-					})
+			change := Change{
+				Package:    newObj.Package,
+				Object:     objName,
+				ObjectType: newObj.Type,
+				ObjectID:   key,
+				File:       fileName,
+			}
+			if oldFileName := fmt.Sprintf("%s/%s", oldObj.Package, filepath.Base(oldObj.Position.Filename)); oldFileName != fileName {
+				change.OldFile = oldFileName
+			}
+			// 检查对象的类型和内容是否变化，如 var 变为 func
+			c := newComparer(mode, oldObj, newObj)
+			if oldObj.Type != newObj.Type || !c.equal(oldObj.Node, newObj.Node) {
+				change.Type = ChangeTypeModified
+				change.Detail = c.detail(oldObj, newObj)
+				result.Changes = append(result.Changes, change)
+				if change.Detail == DetailFields {
+					fields, _ := c.structFieldChanges(oldObj.Node.(*ast.TypeSpec), newObj.Node.(*ast.TypeSpec))
+					fieldChanges = append(fieldChanges, newFieldChanges(change, baseFileName, fields)...)
 				}
+			} else if change.OldFile != "" {
+				change.Type = ChangeTypeMoved
+				result.Changes = append(result.Changes, change)
 			}
 		} else {
 			parts := strings.Split(key, ":")
@@ -219,353 +284,623 @@ func compareResults(old, new *AnalysisResult) *AnalysisResult {
 		}
 	}
 
+	result.Changes = detectRenames(result.Changes, old, new, mode)
+	result.Changes = append(result.Changes, fieldChanges...)
 	return result
 }
 
-// astNodesEqual 比较两个AST节点是否相等
-func astNodesEqual(a, b ast.Node) bool {
-	// 都为 nil 则相等
-	if a == nil && b == nil {
-		return true
-	}
-	// 只有一个为 nil，则不相等。
-	if a == nil || b == nil {
-		return false
+// newFieldChanges 为结构体 change 中发生变化的字段生成变更，
+// fields 的 key 为字段名，value 为字段的变化
+func newFieldChanges(change Change, baseFileName string, fields map[string]ChangeType) []Change {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
 	}
-	// 类型必须一致
-	if reflect.TypeOf(a) != reflect.TypeOf(b) {
-		return false
+	sort.Strings(names)
+	changes := make([]Change, 0, len(names))
+	for _, name := range names {
+		object := change.Object + "." + name
+		changes = append(changes, Change{
+			Type:       fields[name],
+			Package:    change.Package,
+			Object:     object,
+			ObjectType: "field",
+			ObjectID:   parser.GetObjectID(change.Package, baseFileName, object),
+			File:       change.File,
+			OldFile:    change.OldFile,
+		})
 	}
-	switch x := a.(type) {
-	case *ast.File:
-		y := b.(*ast.File)
-		// 比较文件名
-		if !astNodesEqual(x.Name, y.Name) {
-			return false
-		}
-		// 比较所有声明
-		if len(x.Decls) != len(y.Decls) {
-			return false
+	return changes
+}
+
+// detectRenames 将同一个包中同一种类、除名称外完全相同的一对删除和新增合并为重命名，
+// 一个被删除的对象只有唯一一个可以配对的新增对象时才会被视为重命名，反之亦然
+func detectRenames(changes []Change, old, new *AnalysisResult, mode Mode) []Change {
+	var removed, added []int
+	for i, change := range changes {
+		// init 和空标识符会被特殊处理，它们的名称变化会改变程序的行为
+		if change.Object == "init" || change.Object == "_" {
+			continue
+		}
+		switch change.Type {
+		case ChangeTypeRemoved:
+			removed = append(removed, i)
+		case ChangeTypeAdded:
+			added = append(added, i)
 		}
-		for i := range x.Decls {
-			if !astNodesEqual(x.Decls[i], y.Decls[i]) {
-				return false
+	}
+	// key: 被删除的对象的下标, value: 可以与之配对的新增对象的下标
+	candidates := make(map[int][]int)
+	// 每个新增对象可以配对的被删除对象的数量
+	matched := make(map[int]int)
+	for _, r := range removed {
+		for _, a := range added {
+			oldChange, newChange := changes[r], changes[a]
+			if oldChange.Package != newChange.Package || oldChange.ObjectType != newChange.ObjectType {
+				continue
+			}
+			oldObj, newObj := old.Objects[oldChange.ObjectID], new.Objects[newChange.ObjectID]
+			if newComparer(mode, oldObj, newObj).renamedEqual(oldObj.Node, newObj.Node) {
+				candidates[r] = append(candidates[r], a)
+				matched[a]++
 			}
 		}
-		return true
+	}
+
+	drop := make(map[int]struct{})
+	for r, as := range candidates {
+		if len(as) != 1 || matched[as[0]] != 1 {
+			continue
+		}
+		a := as[0]
+		oldChange := changes[r]
+		renamed := changes[a]
+		renamed.Type = ChangeTypeRenamed
+		renamed.OldObject = oldChange.Object
+		renamed.OldObjectID = oldChange.ObjectID
+		if oldChange.File != renamed.File {
+			renamed.OldFile = oldChange.File
+		}
+		changes[a] = renamed
+		drop[r] = struct{}{}
+	}
+	if len(drop) == 0 {
+		return changes
+	}
+	result := make([]Change, 0, len(changes)-len(drop))
+	for i, change := range changes {
+		if _, ok := drop[i]; !ok {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// renamedEqual 判断两个声明除了声明的名称之外是否完全相同
+func (c *comparer) renamedEqual(a, b ast.Node) bool {
+	switch x := a.(type) {
 	case *ast.FuncDecl:
-		y := b.(*ast.FuncDecl)
-		// 比较接收者、名称、函数类型、函数体
-		if !astNodesEqual(x.Recv, y.Recv) {
+		y, ok := b.(*ast.FuncDecl)
+		if !ok {
 			return false
 		}
-		if !astNodesEqual(x.Name, y.Name) {
+		renamed := *y
+		renamed.Name = x.Name
+		return c.equal(x, &renamed)
+	case *ast.TypeSpec:
+		y, ok := b.(*ast.TypeSpec)
+		if !ok {
 			return false
 		}
-		if !astNodesEqual(x.Type, y.Type) {
+		renamed := *y
+		renamed.Name = x.Name
+		return c.equal(x, &renamed)
+	case *ast.ValueSpec:
+		y, ok := b.(*ast.ValueSpec)
+		// 同时声明多个变量时，无法确定被重命名的是哪一个
+		if !ok || len(x.Names) != 1 || len(y.Names) != 1 {
 			return false
 		}
-		if !astNodesEqual(x.Body, y.Body) {
-			return false
+		renamed := *y
+		renamed.Names = x.Names
+		return c.equal(x, &renamed)
+	}
+	return false
+}
+
+// detail 判断发生修改的声明具体修改了哪一部分，存在多处修改时返回影响最大的一种
+func (c *comparer) detail(oldObj, newObj Object) ChangeDetail {
+	if oldObj.Type != newObj.Type {
+		return DetailSignature
+	}
+	switch x := oldObj.Node.(type) {
+	case *ast.FuncDecl:
+		y := newObj.Node.(*ast.FuncDecl)
+		if !c.equal(x.Type, y.Type) {
+			return DetailSignature
 		}
-		return true
-	case *ast.FuncType:
-		y := b.(*ast.FuncType)
-		if !astNodesEqual(x.Params, y.Params) {
-			return false
+		if !c.equal(x.Recv, y.Recv) {
+			return DetailReceiver
 		}
-		if !astNodesEqual(x.Results, y.Results) {
-			return false
+		return DetailBody
+	case *ast.TypeSpec:
+		if _, ok := c.structFieldChanges(x, newObj.Node.(*ast.TypeSpec)); ok {
+			return DetailFields
 		}
-		return true
-	case *ast.FieldList:
-		y := b.(*ast.FieldList)
-		if x == nil || y == nil {
-			return x == y
+		return DetailLayout
+	case *ast.ValueSpec:
+		y := newObj.Node.(*ast.ValueSpec)
+		if !c.equal(x.Type, y.Type) {
+			return DetailSignature
 		}
-		if len(x.List) != len(y.List) {
-			return false
+		return DetailValue
+	}
+	return ""
+}
+
+// structField 是结构体中的一个字段，同时声明的多个字段会被拆开
+type structField struct {
+	name     string
+	embedded bool
+	field    *ast.Field
+}
+
+// structFields 返回结构体类型中的所有字段，spec 不是结构体类型时返回 false
+func structFields(spec *ast.TypeSpec) ([]structField, bool) {
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil, false
+	}
+	var fields []structField
+	for _, field := range st.Fields.List {
+		for _, ident := range parser.FieldIdents(field) {
+			fields = append(fields, structField{name: ident.Name, embedded: len(field.Names) == 0, field: field})
 		}
-		for i := range x.List {
-			if !astNodesEqual(x.List[i], y.List[i]) {
-				return false
+	}
+	return fields, true
+}
+
+// structFieldChanges 比较结构体类型的两个版本，返回被添加、删除或修改了类型和标签的字段。
+// 只有非嵌入字段发生了变化时返回 true，嵌入字段会改变类型的方法集，
+// 字段顺序的变化会影响不带字段名的结构体字面量，空白字段只用于填充，它们都无法缩小到单个字段
+func (c *comparer) structFieldChanges(x, y *ast.TypeSpec) (map[string]ChangeType, bool) {
+	if x.Assign.IsValid() != y.Assign.IsValid() || !c.equal(x.TypeParams, y.TypeParams) {
+		return nil, false
+	}
+	oldFields, ok := structFields(x)
+	if !ok {
+		return nil, false
+	}
+	newFields, ok := structFields(y)
+	if !ok {
+		return nil, false
+	}
+	index := func(fields []structField) (map[string]structField, bool) {
+		m := make(map[string]structField, len(fields))
+		for _, f := range fields {
+			if f.name == "_" {
+				return nil, false
 			}
+			m[f.name] = f
 		}
-		return true
-	case *ast.Field:
-		y := b.(*ast.Field)
-		// 比较字段名列表
-		if len(x.Names) != len(y.Names) {
-			return false
+		return m, true
+	}
+	oldByName, ok := index(oldFields)
+	if !ok {
+		return nil, false
+	}
+	newByName, ok := index(newFields)
+	if !ok {
+		return nil, false
+	}
+
+	changes := make(map[string]ChangeType)
+	// 两个版本中都存在的字段，按照各自版本中的顺序排列
+	var oldOrder, newOrder []string
+	for _, f := range oldFields {
+		nf, ok := newByName[f.name]
+		if !ok {
+			if f.embedded {
+				return nil, false
+			}
+			changes[f.name] = ChangeTypeRemoved
+			continue
 		}
-		for i := range x.Names {
-			if !astNodesEqual(x.Names[i], y.Names[i]) {
-				return false
+		if f.embedded || nf.embedded {
+			if f.embedded != nf.embedded || !c.equal(f.field, nf.field) {
+				return nil, false
 			}
+		} else if !c.equal(f.field.Type, nf.field.Type) || !c.equal(f.field.Tag, nf.field.Tag) {
+			changes[f.name] = ChangeTypeModified
 		}
-		// 比较类型
-		if !astNodesEqual(x.Type, y.Type) {
-			return false
+		oldOrder = append(oldOrder, f.name)
+	}
+	for _, f := range newFields {
+		if _, ok := oldByName[f.name]; !ok {
+			if f.embedded {
+				return nil, false
+			}
+			changes[f.name] = ChangeTypeAdded
+			continue
 		}
-		return true
+		newOrder = append(newOrder, f.name)
+	}
+	if !reflect.DeepEqual(oldOrder, newOrder) || len(changes) == 0 {
+		return nil, false
+	}
+	return changes, true
+}
+
+// astNodesEqual 以 ModeStrict 比较两个AST节点的结构是否相等
+func astNodesEqual(a, b ast.Node) bool {
+	return (&comparer{mode: ModeStrict}).equal(a, b)
+}
+
+// comparer 比较新旧两个版本中的AST节点，equal 的第一个参数总是来自旧版本
+type comparer struct {
+	mode Mode
+	// 旧版本和新版本中节点所在文件的导入, key: 包在文件中的名称, value: 包路径
+	oldImports map[string]string
+	newImports map[string]string
+}
+
+func newComparer(mode Mode, old, new Object) *comparer {
+	return &comparer{mode: mode, oldImports: old.Imports, newImports: new.Imports}
+}
+
+// equal 比较两个AST节点的结构是否相等，节点的位置和注释不参与比较。
+// 所有 go/ast 中的节点类型都需要被显式处理，遇到未知的节点类型时直接 panic，
+// 避免因为漏掉某种节点而错误地判断代码没有变化
+func (c *comparer) equal(a, b ast.Node) bool {
+	if c.mode == ModeSemantic {
+		// 括号只影响代码的写法，运算的优先级已经体现在语法树的结构中
+		a, b = unparen(a), unparen(b)
+	}
+	// 没有参数或返回值时 FieldList 可能为 nil，也可能是空列表(如 func f() ())，两者没有区别
+	if x, ok := a.(*ast.FieldList); ok {
+		if y, ok := b.(*ast.FieldList); ok && (x == nil || y == nil) {
+			return x.NumFields() == 0 && y.NumFields() == 0
+		}
+	}
+	// 都为 nil 则相等，只有一个为 nil 则不相等
+	if isNilNode(a) || isNilNode(b) {
+		return isNilNode(a) && isNilNode(b)
+	}
+	// 类型必须一致
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	switch x := a.(type) {
+	// 表达式
+	case *ast.BadExpr:
+		// 语法错误的代码无法比较内容，总是视为发生了变化
+		return false
 	case *ast.Ident:
 		y := b.(*ast.Ident)
-		if x == nil || y == nil {
-			return x == y
-		}
 		return x.Name == y.Name
+	case *ast.Ellipsis:
+		y := b.(*ast.Ellipsis)
+		return c.equal(x.Elt, y.Elt)
 	case *ast.BasicLit:
 		y := b.(*ast.BasicLit)
-		return x.Value == y.Value && x.Kind == y.Kind
-	case *ast.BlockStmt:
-		y := b.(*ast.BlockStmt)
-		if len(x.List) != len(y.List) {
-			return false
-		}
-		if len(x.List) != len(y.List) {
-			return false
+		if c.mode == ModeSemantic {
+			return literalsEqual(x, y)
 		}
-		for i := range x.List {
-			if !astNodesEqual(x.List[i], y.List[i]) {
-				return false
-			}
-		}
-		return true
-	case *ast.ExprStmt:
-		y := b.(*ast.ExprStmt)
-		return astNodesEqual(x.X, y.X)
-	case *ast.ReturnStmt:
-		y := b.(*ast.ReturnStmt)
-		if len(x.Results) != len(y.Results) {
-			return false
-		}
-		for i := range x.Results {
-			if !astNodesEqual(x.Results[i], y.Results[i]) {
-				return false
-			}
-		}
-		return true
-	case *ast.BinaryExpr:
-		y := b.(*ast.BinaryExpr)
-		return x.Op == y.Op && astNodesEqual(x.X, y.X) && astNodesEqual(x.Y, y.Y)
-	case *ast.CallExpr:
-		y := b.(*ast.CallExpr)
-		if !astNodesEqual(x.Fun, y.Fun) {
-			return false
-		}
-		if len(x.Args) != len(y.Args) {
-			return false
-		}
-		for i := range x.Args {
-			if !astNodesEqual(x.Args[i], y.Args[i]) {
-				return false
-			}
-		}
-		return true
-	case *ast.AssignStmt:
-		y := b.(*ast.AssignStmt)
-		if x.Tok != y.Tok || len(x.Lhs) != len(y.Lhs) || len(x.Rhs) != len(y.Rhs) {
-			return false
-		}
-		for i := range x.Lhs {
-			if !astNodesEqual(x.Lhs[i], y.Lhs[i]) {
-				return false
-			}
-		}
-		for i := range x.Rhs {
-			if !astNodesEqual(x.Rhs[i], y.Rhs[i]) {
-				return false
-			}
-		}
-		return true
-	case *ast.DeclStmt:
-		y := b.(*ast.DeclStmt)
-		return astNodesEqual(x.Decl, y.Decl)
-	case *ast.IfStmt:
-		y := b.(*ast.IfStmt)
-		return astNodesEqual(x.Init, y.Init) &&
-			astNodesEqual(x.Cond, y.Cond) &&
-			astNodesEqual(x.Body, y.Body) &&
-			astNodesEqual(x.Else, y.Else)
-	case *ast.SelectorExpr:
-		y := b.(*ast.SelectorExpr)
-		return astNodesEqual(x.X, y.X) && astNodesEqual(x.Sel, y.Sel)
-	case *ast.UnaryExpr:
-		y := b.(*ast.UnaryExpr)
-		return x.Op == y.Op && astNodesEqual(x.X, y.X)
+		return x.Kind == y.Kind && x.Value == y.Value
+	case *ast.FuncLit:
+		y := b.(*ast.FuncLit)
+		return c.equal(x.Type, y.Type) && c.equal(x.Body, y.Body)
 	case *ast.CompositeLit:
 		y := b.(*ast.CompositeLit)
-		if !astNodesEqual(x.Type, y.Type) {
-			return false
-		}
-		if len(x.Elts) != len(y.Elts) {
-			return false
-		}
-		for i := range x.Elts {
-			if !astNodesEqual(x.Elts[i], y.Elts[i]) {
+		return x.Incomplete == y.Incomplete && c.equal(x.Type, y.Type) && nodeListsEqual(c, x.Elts, y.Elts)
+	case *ast.ParenExpr:
+		y := b.(*ast.ParenExpr)
+		return c.equal(x.X, y.X)
+	case *ast.SelectorExpr:
+		y := b.(*ast.SelectorExpr)
+		// 选择器的左侧是导入的包时比较包路径，同名的包可能来自不同的路径，如 math/rand 与 example.com/rand
+		xPkg, xOk := packageOf(x, c.oldImports)
+		yPkg, yOk := packageOf(y, c.newImports)
+		if xOk || yOk {
+			if !xOk || !yOk || xPkg != yPkg || !c.equal(x.Sel, y.Sel) {
 				return false
 			}
+			// ModeSemantic 忽略通过不同的别名引用同一个包，如 pkg.F 与 alias.F
+			return c.mode == ModeSemantic || c.equal(x.X, y.X)
 		}
-		return true
-	case *ast.StarExpr:
-		y := b.(*ast.StarExpr)
-		return astNodesEqual(x.X, y.X)
-	case *ast.ParenExpr:
-		y := b.(*ast.ParenExpr)
-		return astNodesEqual(x.X, y.X)
+		return c.equal(x.X, y.X) && c.equal(x.Sel, y.Sel)
 	case *ast.IndexExpr:
 		y := b.(*ast.IndexExpr)
-		return astNodesEqual(x.X, y.X) && astNodesEqual(x.Index, y.Index)
+		return c.equal(x.X, y.X) && c.equal(x.Index, y.Index)
+	case *ast.IndexListExpr:
+		// 多个类型参数的泛型实例化，如 Map[K, V]
+		y := b.(*ast.IndexListExpr)
+		return c.equal(x.X, y.X) && nodeListsEqual(c, x.Indices, y.Indices)
 	case *ast.SliceExpr:
 		y := b.(*ast.SliceExpr)
-		return astNodesEqual(x.X, y.X) && astNodesEqual(x.Low, y.Low) && astNodesEqual(x.High, y.High) && astNodesEqual(x.Max, y.Max)
+		return x.Slice3 == y.Slice3 &&
+			c.equal(x.X, y.X) &&
+			c.equal(x.Low, y.Low) &&
+			c.equal(x.High, y.High) &&
+			c.equal(x.Max, y.Max)
+	case *ast.TypeAssertExpr:
+		// Type 为 nil 时表示 type switch 中的 x.(type)
+		y := b.(*ast.TypeAssertExpr)
+		return c.equal(x.X, y.X) && c.equal(x.Type, y.Type)
+	case *ast.CallExpr:
+		// f(xs...) 与 f(xs) 的含义不同
+		y := b.(*ast.CallExpr)
+		return x.Ellipsis.IsValid() == y.Ellipsis.IsValid() &&
+			c.equal(x.Fun, y.Fun) &&
+			nodeListsEqual(c, x.Args, y.Args)
+	case *ast.StarExpr:
+		y := b.(*ast.StarExpr)
+		return c.equal(x.X, y.X)
+	case *ast.UnaryExpr:
+		y := b.(*ast.UnaryExpr)
+		return x.Op == y.Op && c.equal(x.X, y.X)
+	case *ast.BinaryExpr:
+		y := b.(*ast.BinaryExpr)
+		return x.Op == y.Op && c.equal(x.X, y.X) && c.equal(x.Y, y.Y)
 	case *ast.KeyValueExpr:
 		y := b.(*ast.KeyValueExpr)
-		return astNodesEqual(x.Key, y.Key) && astNodesEqual(x.Value, y.Value)
-	case *ast.MapType:
-		y := b.(*ast.MapType)
-		return astNodesEqual(x.Key, y.Key) && astNodesEqual(x.Value, y.Value)
+		return c.equal(x.Key, y.Key) && c.equal(x.Value, y.Value)
+
+	// 类型
 	case *ast.ArrayType:
+		// Len 为 nil 时是切片类型
 		y := b.(*ast.ArrayType)
-		return astNodesEqual(x.Len, y.Len) && astNodesEqual(x.Elt, y.Elt)
+		return c.equal(x.Len, y.Len) && c.equal(x.Elt, y.Elt)
 	case *ast.StructType:
 		y := b.(*ast.StructType)
-		if len(x.Fields.List) != len(y.Fields.List) {
-			return false
-		}
-		for i := range x.Fields.List {
-			if !astNodesEqual(x.Fields.List[i], y.Fields.List[i]) {
-				return false
-			}
-		}
-		return true
+		return x.Incomplete == y.Incomplete && c.equal(x.Fields, y.Fields)
+	case *ast.FuncType:
+		y := b.(*ast.FuncType)
+		return c.equal(x.TypeParams, y.TypeParams) &&
+			c.equal(x.Params, y.Params) &&
+			c.equal(x.Results, y.Results)
 	case *ast.InterfaceType:
 		y := b.(*ast.InterfaceType)
-		if len(x.Methods.List) != len(y.Methods.List) {
-			return false
-		}
-		for i := range x.Methods.List {
-			if !astNodesEqual(x.Methods.List[i], y.Methods.List[i]) {
-				return false
-			}
-		}
-		return true
-	case *ast.Ellipsis:
-		y := b.(*ast.Ellipsis)
-		return astNodesEqual(x.Elt, y.Elt)
+		return x.Incomplete == y.Incomplete && c.equal(x.Methods, y.Methods)
+	case *ast.MapType:
+		y := b.(*ast.MapType)
+		return c.equal(x.Key, y.Key) && c.equal(x.Value, y.Value)
 	case *ast.ChanType:
 		y := b.(*ast.ChanType)
-		return x.Dir == y.Dir && astNodesEqual(x.Value, y.Value)
-	case *ast.ValueSpec:
-		y := b.(*ast.ValueSpec)
-		if len(x.Names) != len(y.Names) {
-			return false
-		}
-		for i := range x.Names {
-			if !astNodesEqual(x.Names[i], y.Names[i]) {
-				return false
-			}
-		}
-		if len(x.Values) != len(y.Values) {
-			return false
-		}
-		for i := range x.Values {
-			if !astNodesEqual(x.Values[i], y.Values[i]) {
-				return false
-			}
-		}
-		return astNodesEqual(x.Type, y.Type)
-	case *ast.BadExpr:
-		y := b.(*ast.BadExpr)
-		return x.From == y.From
-	case *ast.GenDecl:
-		y := b.(*ast.GenDecl)
-		if x.Tok != y.Tok || len(x.Specs) != len(y.Specs) {
-			return false
-		}
-		if len(x.Specs) != len(y.Specs) {
-			return false
-		}
-		for i := range x.Specs {
-			if !astNodesEqual(x.Specs[i], y.Specs[i]) {
-				return false
-			}
-		}
-		return true
-	case *ast.ImportSpec:
-		y := b.(*ast.ImportSpec)
-		return x.Path.Value == y.Path.Value && x.Name != nil && y.Name != nil && x.Name.Name == y.Name.Name
-	case *ast.RangeStmt:
-		y := b.(*ast.RangeStmt)
-		return astNodesEqual(x.Key, y.Key) && astNodesEqual(x.Value, y.Value) && astNodesEqual(x.X, y.X) && astNodesEqual(x.Body, y.Body)
-	case *ast.CaseClause:
-		y := b.(*ast.CaseClause)
-		if len(x.List) != len(y.List) {
-			return false
-		}
-		for i := range x.List {
-			if !astNodesEqual(x.List[i], y.List[i]) {
-				return false
-			}
-		}
+		return x.Dir == y.Dir && c.equal(x.Value, y.Value)
+
+	// 语句
+	case *ast.BadStmt:
+		return false
+	case *ast.DeclStmt:
+		y := b.(*ast.DeclStmt)
+		return c.equal(x.Decl, y.Decl)
+	case *ast.EmptyStmt:
+		// 显式的分号与隐式的空语句没有区别
 		return true
-	case *ast.SwitchStmt:
-		y := b.(*ast.SwitchStmt)
-		return astNodesEqual(x.Init, y.Init) && astNodesEqual(x.Tag, y.Tag) && astNodesEqual(x.Body, y.Body)
-	case *ast.TypeSpec:
-		y := b.(*ast.TypeSpec)
-		return astNodesEqual(x.Name, y.Name) && astNodesEqual(x.Type, y.Type)
-	case *ast.TypeAssertExpr:
-		y := b.(*ast.TypeAssertExpr)
-		return astNodesEqual(x.X, y.X) && astNodesEqual(x.Type, y.Type)
-	case *ast.FuncLit:
-		y := b.(*ast.FuncLit)
-		return astNodesEqual(x.Type, y.Type) && astNodesEqual(x.Body, y.Body)
-	case *ast.DeferStmt:
-		y := b.(*ast.DeferStmt)
-		return astNodesEqual(x.Call, y.Call)
 	case *ast.LabeledStmt:
 		y := b.(*ast.LabeledStmt)
-		return astNodesEqual(x.Label, y.Label) && astNodesEqual(x.Stmt, y.Stmt)
-	case *ast.GoStmt:
-		y := b.(*ast.GoStmt)
-		return astNodesEqual(x.Call, y.Call)
+		return c.equal(x.Label, y.Label) && c.equal(x.Stmt, y.Stmt)
+	case *ast.ExprStmt:
+		y := b.(*ast.ExprStmt)
+		return c.equal(x.X, y.X)
 	case *ast.SendStmt:
 		y := b.(*ast.SendStmt)
-		return astNodesEqual(x.Chan, y.Chan) && astNodesEqual(x.Value, y.Value)
+		return c.equal(x.Chan, y.Chan) && c.equal(x.Value, y.Value)
 	case *ast.IncDecStmt:
 		y := b.(*ast.IncDecStmt)
-		return astNodesEqual(x.X, y.X) && x.Tok == y.Tok
+		return x.Tok == y.Tok && c.equal(x.X, y.X)
+	case *ast.AssignStmt:
+		y := b.(*ast.AssignStmt)
+		return x.Tok == y.Tok && nodeListsEqual(c, x.Lhs, y.Lhs) && nodeListsEqual(c, x.Rhs, y.Rhs)
+	case *ast.GoStmt:
+		y := b.(*ast.GoStmt)
+		return c.equal(x.Call, y.Call)
+	case *ast.DeferStmt:
+		y := b.(*ast.DeferStmt)
+		return c.equal(x.Call, y.Call)
+	case *ast.ReturnStmt:
+		y := b.(*ast.ReturnStmt)
+		return nodeListsEqual(c, x.Results, y.Results)
 	case *ast.BranchStmt:
 		y := b.(*ast.BranchStmt)
-		return x.Tok == y.Tok && astNodesEqual(x.Label, y.Label)
-	case *ast.BadStmt:
-		y := b.(*ast.BadStmt)
-		return x.From == y.From
-	case *ast.ForStmt:
-		y := b.(*ast.ForStmt)
-		return astNodesEqual(x.Init, y.Init) && astNodesEqual(x.Cond, y.Cond) && astNodesEqual(x.Post, y.Post) && astNodesEqual(x.Body, y.Body)
-	case *ast.SelectStmt:
-		y := b.(*ast.SelectStmt)
-		return astNodesEqual(x.Body, y.Body)
+		return x.Tok == y.Tok && c.equal(x.Label, y.Label)
+	case *ast.BlockStmt:
+		y := b.(*ast.BlockStmt)
+		return nodeListsEqual(c, x.List, y.List)
+	case *ast.IfStmt:
+		y := b.(*ast.IfStmt)
+		return c.equal(x.Init, y.Init) &&
+			c.equal(x.Cond, y.Cond) &&
+			c.equal(x.Body, y.Body) &&
+			c.equal(x.Else, y.Else)
+	case *ast.CaseClause:
+		// List 为 nil 时是 default 分支
+		y := b.(*ast.CaseClause)
+		return (x.List == nil) == (y.List == nil) &&
+			nodeListsEqual(c, x.List, y.List) &&
+			nodeListsEqual(c, x.Body, y.Body)
+	case *ast.SwitchStmt:
+		y := b.(*ast.SwitchStmt)
+		return c.equal(x.Init, y.Init) && c.equal(x.Tag, y.Tag) && c.equal(x.Body, y.Body)
+	case *ast.TypeSwitchStmt:
+		y := b.(*ast.TypeSwitchStmt)
+		return c.equal(x.Init, y.Init) && c.equal(x.Assign, y.Assign) && c.equal(x.Body, y.Body)
 	case *ast.CommClause:
+		// Comm 为 nil 时是 default 分支
 		y := b.(*ast.CommClause)
-		if !astNodesEqual(x.Comm, y.Comm) {
-			return false
-		}
-		if len(x.Body) != len(y.Body) {
+		return c.equal(x.Comm, y.Comm) && nodeListsEqual(c, x.Body, y.Body)
+	case *ast.SelectStmt:
+		y := b.(*ast.SelectStmt)
+		return c.equal(x.Body, y.Body)
+	case *ast.ForStmt:
+		y := b.(*ast.ForStmt)
+		return c.equal(x.Init, y.Init) &&
+			c.equal(x.Cond, y.Cond) &&
+			c.equal(x.Post, y.Post) &&
+			c.equal(x.Body, y.Body)
+	case *ast.RangeStmt:
+		// Tok 区分 for k := range 与 for k = range
+		y := b.(*ast.RangeStmt)
+		return x.Tok == y.Tok &&
+			c.equal(x.Key, y.Key) &&
+			c.equal(x.Value, y.Value) &&
+			c.equal(x.X, y.X) &&
+			c.equal(x.Body, y.Body)
+
+	// 声明
+	case *ast.ImportSpec:
+		y := b.(*ast.ImportSpec)
+		return c.equal(x.Name, y.Name) && c.equal(x.Path, y.Path)
+	case *ast.ValueSpec:
+		y := b.(*ast.ValueSpec)
+		return nodeListsEqual(c, x.Names, y.Names) && c.equal(x.Type, y.Type) && nodeListsEqual(c, x.Values, y.Values)
+	case *ast.TypeSpec:
+		// Assign 有效时是类型别名，type A = B 与 type A B 的含义不同
+		y := b.(*ast.TypeSpec)
+		return x.Assign.IsValid() == y.Assign.IsValid() &&
+			c.equal(x.Name, y.Name) &&
+			c.equal(x.TypeParams, y.TypeParams) &&
+			c.equal(x.Type, y.Type)
+	case *ast.BadDecl:
+		return false
+	case *ast.GenDecl:
+		y := b.(*ast.GenDecl)
+		return x.Tok == y.Tok && nodeListsEqual(c, x.Specs, y.Specs)
+	case *ast.FuncDecl:
+		y := b.(*ast.FuncDecl)
+		return c.equal(x.Recv, y.Recv) &&
+			c.equal(x.Name, y.Name) &&
+			c.equal(x.Type, y.Type) &&
+			c.equal(x.Body, y.Body)
+
+	// 其他节点
+	case *ast.Comment, *ast.CommentGroup:
+		// 注释不影响程序的行为
+		return true
+	case *ast.Field:
+		y := b.(*ast.Field)
+		return nodeListsEqual(c, x.Names, y.Names) && c.equal(x.Type, y.Type) && c.equal(x.Tag, y.Tag)
+	case *ast.FieldList:
+		y := b.(*ast.FieldList)
+		return nodeListsEqual(c, x.List, y.List)
+	case *ast.File:
+		y := b.(*ast.File)
+		return c.equal(x.Name, y.Name) && nodeListsEqual(c, x.Decls, y.Decls)
+	case *ast.Package:
+		y := b.(*ast.Package)
+		if x.Name != y.Name || len(x.Files) != len(y.Files) {
 			return false
 		}
-		for i := range x.Body {
-			if !astNodesEqual(x.Body[i], y.Body[i]) {
+		for name, file := range x.Files {
+			if !c.equal(file, y.Files[name]) {
 				return false
 			}
 		}
 		return true
-	case *ast.TypeSwitchStmt:
-		y := b.(*ast.TypeSwitchStmt)
-		return astNodesEqual(x.Init, y.Init) && astNodesEqual(x.Assign, y.Assign) && astNodesEqual(x.Body, y.Body)
 	default:
 		panic(fmt.Sprintf("未处理的节点类型: %T, a: %v, b: %v\n", x, a, b))
 	}
 }
+
+// nodeListsEqual 按顺序比较两组节点是否相等
+func nodeListsEqual[T ast.Node](c *comparer, a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !c.equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNilNode 判断节点是否为 nil，包括值为 nil 的指针，如函数声明中为 nil 的 *ast.BlockStmt
+func isNilNode(n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// unparen 去掉表达式外层的所有括号
+func unparen(n ast.Node) ast.Node {
+	for {
+		p, ok := n.(*ast.ParenExpr)
+		if !ok || p == nil {
+			return n
+		}
+		n = p.X
+	}
+}
+
+// literalsEqual 判断两个字面量的值是否相等，如 0x10 与 16、"a" 与 `a`，
+// 字面量的种类不同时(如 'a' 与 97)，它们的默认类型不同，视为不相等
+func literalsEqual(x, y *ast.BasicLit) bool {
+	if x.Kind != y.Kind {
+		return false
+	}
+	if x.Value == y.Value {
+		return true
+	}
+	xv := constant.MakeFromLiteral(x.Value, x.Kind, 0)
+	yv := constant.MakeFromLiteral(y.Value, y.Kind, 0)
+	if xv.Kind() == constant.Unknown || yv.Kind() == constant.Unknown {
+		return false
+	}
+	return constant.Compare(xv, token.EQL, yv)
+}
+
+// packageOf 返回选择器表达式 pkg.Name 中 pkg 对应的包路径，
+// 选择器的左侧不是导入的包时返回 false
+func packageOf(sel *ast.SelectorExpr, imports map[string]string) (string, bool) {
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	path, ok := imports[ident.Name]
+	return path, ok
+}
+
+// fileImports 返回文件中导入的包, key: 包在文件中的名称, value: 包路径，
+// 点导入和匿名导入不会出现在选择器中，因此被忽略
+func fileImports(file *ast.File) map[string]string {
+	imports := make(map[string]string, len(file.Imports))
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := importPathToAssumedName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "." || name == "_" {
+			continue
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// importPathToAssumedName 根据导入路径推测包名，与 goimports 的规则相同，
+// 如 "gopkg.in/yaml.v3" 的包名为 yaml，"github.com/go-redis/redis/v8" 的包名为 redis
+func importPathToAssumedName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			if dir := path.Dir(importPath); dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_')
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
@@ -0,0 +1,520 @@
+package astdiff
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// parseDecl 解析 src 中的最后一个顶层声明，src 不需要包含 package 子句
+func parseDecl(t *testing.T, src string) ast.Node {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "a.go", "package p\n"+src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", src, err)
+	}
+	return f.Decls[len(f.Decls)-1]
+}
+
+func TestAstNodesEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want bool
+	}{
+		{
+			name: "comments",
+			old:  "func f() int {\n\t// old\n\treturn 1\n}",
+			new:  "// doc\nfunc f() int {\n\treturn 1 // new\n}",
+			want: true,
+		},
+		{
+			name: "layout",
+			old:  "func f() int { return 1 }",
+			new:  "func f() int {\n\n\treturn 1\n}",
+			want: true,
+		},
+		{
+			name: "literal",
+			old:  "func f() int { return 1 }",
+			new:  "func f() int { return 2 }",
+			want: false,
+		},
+		{
+			name: "func-without-body",
+			old:  "func f()",
+			new:  "func f() {}",
+			want: false,
+		},
+		{
+			name: "empty-results",
+			old:  "func f() () {}",
+			new:  "func f() {}",
+			want: true,
+		},
+		{
+			name: "empty-results-and-result",
+			old:  "func f() () {}",
+			new:  "func f() int { return 0 }",
+			want: false,
+		},
+		{
+			name: "func-type-params",
+			old:  "func f[T any](x T) {}",
+			new:  "func f[T comparable](x T) {}",
+			want: false,
+		},
+		{
+			name: "type-params",
+			old:  "type S[T any] struct{ v T }",
+			new:  "type S[T any, U any] struct{ v T }",
+			want: false,
+		},
+		{
+			name: "index-list-expr",
+			old:  "var m Map[int, string]",
+			new:  "var m Map[int, int]",
+			want: false,
+		},
+		{
+			name: "type-alias",
+			old:  "type A = int",
+			new:  "type A int",
+			want: false,
+		},
+		{
+			name: "struct-tag",
+			old:  "type S struct {\n\tA int `json:\"a\"`\n}",
+			new:  "type S struct {\n\tA int `json:\"b\"`\n}",
+			want: false,
+		},
+		{
+			name: "chan-dir",
+			old:  "var c chan<- int",
+			new:  "var c <-chan int",
+			want: false,
+		},
+		{
+			name: "case-body",
+			old:  "func f(x int) {\n\tswitch x {\n\tcase 1:\n\t\ta()\n\t}\n}",
+			new:  "func f(x int) {\n\tswitch x {\n\tcase 1:\n\t\tb()\n\t}\n}",
+			want: false,
+		},
+		{
+			name: "type-switch",
+			old:  "func f(x any) {\n\tswitch v := x.(type) {\n\tcase int:\n\t\t_ = v\n\t}\n}",
+			new:  "func f(x any) {\n\tswitch v := x.(type) {\n\tcase string:\n\t\t_ = v\n\t}\n}",
+			want: false,
+		},
+		{
+			name: "range-tok",
+			old:  "func f(m map[int]int) {\n\tvar k int\n\tfor k = range m {\n\t}\n\t_ = k\n}",
+			new:  "func f(m map[int]int) {\n\tvar k int\n\tfor k := range m {\n\t\t_ = k\n\t}\n}",
+			want: false,
+		},
+		{
+			name: "variadic-call",
+			old:  "func f(xs []any) { g(xs...) }",
+			new:  "func f(xs []any) { g(xs) }",
+			want: false,
+		},
+		{
+			name: "slice3",
+			old:  "func f(s []int) []int { return s[1:2:3] }",
+			new:  "func f(s []int) []int { return s[1:2:4] }",
+			want: false,
+		},
+		{
+			name: "labeled",
+			old:  "func f() {\nL:\n\tfor {\n\t\tbreak L\n\t}\n}",
+			new:  "func f() {\nM:\n\tfor {\n\t\tbreak M\n\t}\n}",
+			want: false,
+		},
+		{
+			name: "select",
+			old:  "func f(c chan int) {\n\tselect {\n\tcase v := <-c:\n\t\t_ = v\n\tdefault:\n\t}\n}",
+			new:  "func f(c chan int) {\n\tselect {\n\tcase v := <-c:\n\t\t_ = v\n\t}\n}",
+			want: false,
+		},
+		{
+			name: "goroutine",
+			old:  "func f() { go a() }",
+			new:  "func f() { go b() }",
+			want: false,
+		},
+		{
+			name: "defer",
+			old:  "func f() { defer a() }",
+			new:  "func f() { a() }",
+			want: false,
+		},
+		{
+			name: "func-lit",
+			old:  "var f = func(x int) int { return x * 2 }",
+			new:  "var f = func(x int) int {\n\treturn x * 2\n}",
+			want: true,
+		},
+		{
+			name: "send-and-incdec",
+			old:  "func f(c chan int, i int) {\n\tc <- i\n\ti++\n}",
+			new:  "func f(c chan int, i int) {\n\tc <- i\n\ti--\n}",
+			want: false,
+		},
+		{
+			name: "composite-lit",
+			old:  "var s = S{A: 1, B: []int{1, 2}}",
+			new:  "var s = S{\n\tA: 1,\n\tB: []int{1, 2},\n}",
+			want: true,
+		},
+		{
+			name: "interface-methods",
+			old:  "type I interface {\n\tM(int) error\n}",
+			new:  "type I interface {\n\tM(int) error\n\tN()\n}",
+			want: false,
+		},
+		{
+			name: "goto",
+			old:  "func f() {\n\tgoto L\nL:\n}",
+			new:  "func f() {\n\tgoto L\nL:\n\t;\n}",
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := parseDecl(t, tt.old), parseDecl(t, tt.new)
+			if got := astNodesEqual(old, new); got != tt.want {
+				t.Errorf("astNodesEqual() = %v, want %v", got, tt.want)
+			}
+			// 同样的代码出现在文件中的不同位置时，比较结果不受影响
+			shifted := parseDecl(t, strings.Repeat("\n", 10)+tt.old)
+			if !astNodesEqual(old, shifted) {
+				t.Errorf("astNodesEqual() = false for the same code at different positions")
+			}
+		})
+	}
+}
+
+// unknownNode 是 go/ast 之外的节点类型
+type unknownNode struct{}
+
+func (unknownNode) Pos() token.Pos { return token.NoPos }
+func (unknownNode) End() token.Pos { return token.NoPos }
+
+func TestAstNodesEqualUnknownNode(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("astNodesEqual() should panic on unknown node type")
+		}
+	}()
+	astNodesEqual(&unknownNode{}, &unknownNode{})
+}
+
+func TestComparerSemantic(t *testing.T) {
+	// parse 解析 src 中的最后一个顶层声明及文件中的导入
+	parse := func(src string) Object {
+		f, err := parser.ParseFile(token.NewFileSet(), "a.go", "package p\n"+src, parser.ParseComments)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", src, err)
+		}
+		return Object{Node: f.Decls[len(f.Decls)-1], Imports: fileImports(f)}
+	}
+	tests := []struct {
+		name       string
+		old        string
+		new        string
+		wantStrict bool
+		want       bool
+	}{
+		{
+			name:       "parentheses",
+			old:        "func f(a, b int) int { return (a + b) * 2 }",
+			new:        "func f(a, b int) int { return ((a + b) * (2)) }",
+			wantStrict: false,
+			want:       true,
+		},
+		{
+			name:       "precedence",
+			old:        "func f(a, b int) int { return (a + b) * 2 }",
+			new:        "func f(a, b int) int { return a + b*2 }",
+			wantStrict: false,
+			want:       false,
+		},
+		{
+			name:       "hex-literal",
+			old:        "const N = 0x10",
+			new:        "const N = 16",
+			wantStrict: false,
+			want:       true,
+		},
+		{
+			name:       "changed-literal",
+			old:        "const N = 0x10",
+			new:        "const N = 17",
+			wantStrict: false,
+			want:       false,
+		},
+		{
+			name:       "raw-string",
+			old:        "var s = \"a\\tb\"",
+			new:        "var s = `a\tb`",
+			wantStrict: false,
+			want:       true,
+		},
+		{
+			name:       "rune-and-int",
+			old:        "var r = 'a'",
+			new:        "var r = 97",
+			wantStrict: false,
+			want:       false,
+		},
+		{
+			name:       "import-alias",
+			old:        "import j \"encoding/json\"\nfunc f(v any) ([]byte, error) { return j.Marshal(v) }",
+			new:        "import \"encoding/json\"\nfunc f(v any) ([]byte, error) { return json.Marshal(v) }",
+			wantStrict: false,
+			want:       true,
+		},
+		{
+			name:       "same-name-different-package",
+			old:        "import \"math/rand\"\nfunc f() int { return rand.Int() }",
+			new:        "import rand \"example.com/rand\"\nfunc f() int { return rand.Int() }",
+			wantStrict: false,
+			want:       false,
+		},
+		{
+			name:       "versioned-import",
+			old:        "import \"gopkg.in/yaml.v3\"\nvar m = yaml.Marshal",
+			new:        "import y \"gopkg.in/yaml.v3\"\nvar m = y.Marshal",
+			wantStrict: false,
+			want:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := parse(tt.old), parse(tt.new)
+			if got := newComparer(ModeStrict, old, new).equal(old.Node, new.Node); got != tt.wantStrict {
+				t.Errorf("strict equal() = %v, want %v", got, tt.wantStrict)
+			}
+			if got := newComparer(ModeSemantic, old, new).equal(old.Node, new.Node); got != tt.want {
+				t.Errorf("semantic equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparerDetail(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want ChangeDetail
+	}{
+		{name: "body", old: "func f() int { return 1 }", new: "func f() int { return 2 }", want: DetailBody},
+		{name: "params", old: "func f(x int) {}", new: "func f(x int64) {}", want: DetailSignature},
+		{name: "type-params", old: "func f[T any]() {}", new: "func f[T comparable]() {}", want: DetailSignature},
+		{name: "signature-and-body", old: "func f() int { return 1 }", new: "func f() int64 { return 2 }", want: DetailSignature},
+		{name: "receiver", old: "func (a *T) M() {}", new: "func (b *T) M() {}", want: DetailReceiver},
+		{name: "struct-field", old: "type S struct{ A int }", new: "type S struct{ A, B int }", want: DetailFields},
+		{name: "struct-tag", old: "type S struct {\n\tA int `json:\"a\"`\n}", new: "type S struct {\n\tA int `json:\"b\"`\n}", want: DetailFields},
+		{name: "struct-embedded", old: "type S struct{ A int }", new: "type S struct {\n\tBase\n\tA int\n}", want: DetailLayout},
+		{name: "struct-order", old: "type S struct{ A, B int }", new: "type S struct{ B, A int }", want: DetailLayout},
+		{name: "struct-to-interface", old: "type S struct{ A int }", new: "type S interface{ A() int }", want: DetailLayout},
+		{name: "const-value", old: "const N = 1", new: "const N = 2", want: DetailValue},
+		{name: "var-type", old: "var x int = 1", new: "var x int64 = 1", want: DetailSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := Object{Type: "decl", Node: parseDecl(t, tt.old)}
+			new := Object{Type: "decl", Node: parseDecl(t, tt.new)}
+			// GenDecl 中只有一个声明，与 analyzePackage 一样使用其中的 spec 进行比较
+			if d, ok := old.Node.(*ast.GenDecl); ok {
+				old.Node = d.Specs[0]
+			}
+			if d, ok := new.Node.(*ast.GenDecl); ok {
+				new.Node = d.Specs[0]
+			}
+			if got := newComparer(ModeStrict, old, new).detail(old, new); got != tt.want {
+				t.Errorf("detail() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructFieldChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want map[string]ChangeType
+	}{
+		{
+			name: "added-removed-modified",
+			old:  "type S struct {\n\tA int\n\tB string\n\tC bool\n}",
+			new:  "type S struct {\n\tA int\n\tB []string\n\tD float64\n}",
+			want: map[string]ChangeType{"B": ChangeTypeModified, "C": ChangeTypeRemoved, "D": ChangeTypeAdded},
+		},
+		{
+			name: "unchanged-embedded",
+			old:  "type S struct {\n\t*Base\n\tA int\n}",
+			new:  "type S struct {\n\t*Base\n\tA int\n\tB int\n}",
+			want: map[string]ChangeType{"B": ChangeTypeAdded},
+		},
+		{name: "embedded-type", old: "type S struct{ Base }", new: "type S struct{ *Base }"},
+		{name: "blank", old: "type S struct{ _ int }", new: "type S struct{ _ int64 }"},
+		{name: "type-params", old: "type S[T any] struct{ A T }", new: "type S[T comparable] struct{ A T; B T }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := parseDecl(t, tt.old).(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+			new := parseDecl(t, tt.new).(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+			got, ok := (&comparer{mode: ModeStrict}).structFieldChanges(old, new)
+			if ok != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("structFieldChanges() = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRenames(t *testing.T) {
+	// decl 是测试用的一个顶层声明
+	type decl struct {
+		id   string
+		pkg  string
+		file string
+		kind string
+		src  string
+	}
+	// summary 只保留与重命名相关的字段
+	type summary struct {
+		Type        ChangeType
+		ObjectID    string
+		OldObjectID string
+		OldFile     string
+	}
+	objects := func(decls []decl) *AnalysisResult {
+		result := &AnalysisResult{Objects: make(map[string]Object)}
+		for _, d := range decls {
+			node := parseDecl(t, d.src)
+			if g, ok := node.(*ast.GenDecl); ok {
+				node = g.Specs[0]
+			}
+			result.Objects[d.id] = Object{
+				Type:     d.kind,
+				Package:  d.pkg,
+				Position: token.Position{Filename: d.file},
+				Node:     node,
+			}
+		}
+		return result
+	}
+	tests := []struct {
+		name string
+		old  []decl
+		new  []decl
+		want []summary
+	}{
+		{
+			name: "unique-pair",
+			old:  []decl{{id: "p:A", pkg: "p", file: "a.go", kind: "func", src: "func A() int { return 1 }"}},
+			new:  []decl{{id: "p:B", pkg: "p", file: "a.go", kind: "func", src: "func B() int { return 1 }"}},
+			want: []summary{{Type: ChangeTypeRenamed, ObjectID: "p:B", OldObjectID: "p:A"}},
+		},
+		{
+			name: "two-removed-candidates",
+			old: []decl{
+				{id: "p:A", pkg: "p", file: "a.go", kind: "func", src: "func A() int { return 1 }"},
+				{id: "p:C", pkg: "p", file: "a.go", kind: "func", src: "func C() int { return 1 }"},
+			},
+			new: []decl{{id: "p:B", pkg: "p", file: "a.go", kind: "func", src: "func B() int { return 1 }"}},
+			want: []summary{
+				{Type: ChangeTypeRemoved, ObjectID: "p:A"},
+				{Type: ChangeTypeAdded, ObjectID: "p:B"},
+				{Type: ChangeTypeRemoved, ObjectID: "p:C"},
+			},
+		},
+		{
+			name: "two-added-candidates",
+			old:  []decl{{id: "p:A", pkg: "p", file: "a.go", kind: "func", src: "func A() int { return 1 }"}},
+			new: []decl{
+				{id: "p:B", pkg: "p", file: "a.go", kind: "func", src: "func B() int { return 1 }"},
+				{id: "p:C", pkg: "p", file: "a.go", kind: "func", src: "func C() int { return 1 }"},
+			},
+			want: []summary{
+				{Type: ChangeTypeRemoved, ObjectID: "p:A"},
+				{Type: ChangeTypeAdded, ObjectID: "p:B"},
+				{Type: ChangeTypeAdded, ObjectID: "p:C"},
+			},
+		},
+		{
+			name: "different-kind",
+			old:  []decl{{id: "p:A", pkg: "p", file: "a.go", kind: "var", src: "var A = 1"}},
+			new:  []decl{{id: "p:B", pkg: "p", file: "a.go", kind: "const", src: "const B = 1"}},
+			want: []summary{
+				{Type: ChangeTypeRemoved, ObjectID: "p:A"},
+				{Type: ChangeTypeAdded, ObjectID: "p:B"},
+			},
+		},
+		{
+			name: "different-package",
+			old:  []decl{{id: "p:A", pkg: "p", file: "a.go", kind: "func", src: "func A() int { return 1 }"}},
+			new:  []decl{{id: "q:B", pkg: "q", file: "a.go", kind: "func", src: "func B() int { return 1 }"}},
+			want: []summary{
+				{Type: ChangeTypeRemoved, ObjectID: "p:A"},
+				{Type: ChangeTypeAdded, ObjectID: "q:B"},
+			},
+		},
+		{
+			name: "multi-name-value-spec",
+			old:  []decl{{id: "p:a", pkg: "p", file: "a.go", kind: "var", src: "var a, x int"}},
+			new:  []decl{{id: "p:c", pkg: "p", file: "a.go", kind: "var", src: "var c, x int"}},
+			want: []summary{
+				{Type: ChangeTypeRemoved, ObjectID: "p:a"},
+				{Type: ChangeTypeAdded, ObjectID: "p:c"},
+			},
+		},
+		{
+			name: "init",
+			old:  []decl{{id: "p/a.go:init", pkg: "p", file: "a.go", kind: "func", src: "func init() { println() }"}},
+			new:  []decl{{id: "p/b.go:init", pkg: "p", file: "b.go", kind: "func", src: "func init() { println() }"}},
+			want: []summary{
+				{Type: ChangeTypeRemoved, ObjectID: "p/a.go:init"},
+				{Type: ChangeTypeAdded, ObjectID: "p/b.go:init"},
+			},
+		},
+		{
+			name: "blank",
+			old:  []decl{{id: "p/a.go:_", pkg: "p", file: "a.go", kind: "var", src: "var _ = 1"}},
+			new:  []decl{{id: "p/b.go:_", pkg: "p", file: "b.go", kind: "var", src: "var _ = 1"}},
+			want: []summary{
+				{Type: ChangeTypeRemoved, ObjectID: "p/a.go:_"},
+				{Type: ChangeTypeAdded, ObjectID: "p/b.go:_"},
+			},
+		},
+		{
+			name: "renamed-and-moved",
+			old:  []decl{{id: "p:A", pkg: "p", file: "a.go", kind: "type", src: "type A struct{ X int }"}},
+			new:  []decl{{id: "p:B", pkg: "p", file: "b.go", kind: "type", src: "type B struct{ X int }"}},
+			want: []summary{{Type: ChangeTypeRenamed, ObjectID: "p:B", OldObjectID: "p:A", OldFile: "p/a.go"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := compareResults(objects(tt.old), objects(tt.new), ModeStrict)
+			got := make([]summary, 0, len(result.Changes))
+			for _, c := range result.Changes {
+				got = append(got, summary{Type: c.Type, ObjectID: c.ObjectID, OldObjectID: c.OldObjectID, OldFile: c.OldFile})
+			}
+			sort.Slice(got, func(i, j int) bool {
+				return got[i].ObjectID < got[j].ObjectID
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareResults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
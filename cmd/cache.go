@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/bootun/veronica/parser"
+)
+
+// dependencyCache 将 commit 的依赖图缓存在 dir 中，
+// 依赖图与 commit、项目在仓库中的位置、Go 版本以及 veronica 的版本有关
+type dependencyCache struct {
+	dir  string
+	repo *gitRepo
+}
+
+// path 返回 commit 的依赖图缓存文件的路径
+func (c *dependencyCache) path(commit string) (string, error) {
+	hash, err := c.repo.resolveCommit(commit)
+	if err != nil {
+		return "", err
+	}
+	name := runtime.Version() + "-" + Version
+	// 同一个仓库中可能有多个项目
+	if prefix := strings.Trim(c.repo.prefix, "/"); prefix != "" {
+		name += "-" + strings.ReplaceAll(prefix, "/", "_")
+	}
+	return filepath.Join(c.dir, hash, name+".json"), nil
+}
+
+// load 读取 commit 的依赖图，缓存不存在或无法使用时返回 false
+func (c *dependencyCache) load(commit string) (*parser.DependencyInfo, bool) {
+	path, err := c.path(commit)
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	deps := &parser.DependencyInfo{}
+	if err := json.Unmarshal(data, deps); err != nil {
+		return nil, false
+	}
+	return deps, true
+}
+
+// save 将 commit 的依赖图写入缓存
+func (c *dependencyCache) save(commit string, deps *parser.DependencyInfo) error {
+	path, err := c.path(commit)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(deps)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", filepath.Dir(path), err)
+	}
+	// 先写入临时文件再重命名，避免并发运行时读到不完整的缓存
+	tmp, err := os.CreateTemp(filepath.Dir(path), "deps-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
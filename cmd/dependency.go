@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/bootun/veronica/parser"
 	"github.com/spf13/cobra"
@@ -13,9 +15,8 @@ var dependencyCmd = &cobra.Command{
 	Use:   "dependency",
 	Short: "object dependency analysis",
 	Run: func(cmd *cobra.Command, args []string) {
-		if targetID == "" {
-			cmd.Usage()
-			os.Exit(1)
+		if strings.TrimSpace(targetID) == "" {
+			log.Fatalf("--target is required")
 		}
 		pkgs, err := parser.LoadPackages(repo)
 		if err != nil {
@@ -25,11 +26,13 @@ var dependencyCmd = &cobra.Command{
 		if err != nil {
 			log.Fatalf("build dependency: %s", err)
 		}
-		deps, err := dependencyInfo.GetDependency(targetID)
+		target := resolveTarget(dependencyInfo, targetID)
+		deps, err := dependencyInfo.Traverse(target, parser.Direction(direction), depth)
 		if err != nil {
 			log.Fatalf("get dependency: %s", err)
 		}
-		fmt.Printf("target: %s\n", targetID)
+		sort.Strings(deps)
+		fmt.Printf("target: %s\n", target)
 		for _, dep := range deps {
 			fmt.Println(dep)
 		}
@@ -38,9 +41,42 @@ var dependencyCmd = &cobra.Command{
 
 var (
 	targetID string
+	// direction 是查询依赖的方向，forward 查询 target 依赖的对象，reverse 查询依赖 target 的对象
+	direction string
+	// depth 限制查询的步数，小于等于 0 时不限制
+	depth int
 )
 
 func init() {
-	dependencyCmd.Flags().StringVarP(&targetID, "target", "t", "", "target")
+	dependencyCmd.Flags().StringVarP(&targetID, "target", "t", "", "target, e.g. pkg:Name, pkg.Name, (*T).M, a service name or file.go:line")
 	dependencyCmd.Flags().StringVarP(&repo, "repo", "r", ".", "repo path")
+	dependencyCmd.Flags().StringVar(&direction, "direction", string(parser.DirectionReverse), "query direction (forward: what target depends on, reverse: what depends on target)")
+	dependencyCmd.Flags().IntVar(&depth, "depth", 0, "maximum number of steps from target, 0 means unlimited")
+}
+
+// resolveTarget 将用户输入的 target 解析为唯一的节点ID，target 可以是 veronica.yaml 中的服务名称，
+// 或者 parser.DependencyInfo.Resolve 支持的任意写法。找不到或者存在歧义时直接退出
+func resolveTarget(dependencyInfo *parser.DependencyInfo, target string) string {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		log.Fatalf("--target is required")
+	}
+	// 没有配置文件时，target 不会是服务名称
+	if project, err := parser.NewProject(repo); err == nil {
+		if svc, ok := findService(project.Services, target); ok {
+			return svc.Entrypoint
+		}
+	}
+	ids, err := dependencyInfo.Resolve(target)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	if len(ids) > 1 {
+		fmt.Fprintf(os.Stderr, "target %s is ambiguous, candidates:\n", target)
+		for _, id := range ids {
+			fmt.Fprintf(os.Stderr, "  %s\n", id)
+		}
+		os.Exit(1)
+	}
+	return ids[0]
 }
@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gitRepo 表示项目所在的 git 仓库，所有的 git 命令都在项目目录下执行
+type gitRepo struct {
+	// dir 是项目的根目录，即 --repo 指定的目录
+	dir string
+	// root 是 git 仓库的根目录
+	root string
+	// prefix 是项目根目录相对于 git 仓库根目录的路径，
+	// Go module 位于仓库的子目录时不为空，如 "backend/"
+	prefix string
+	// keep 不为 nil 时，导出 commit 时只导出 keep 返回 true 的文件，
+	// 以及包含 .go 文件的目录中的其他文件(它们可能被 //go:embed 或 cgo 引用)
+	keep func(name string) bool
+	// worktreeDir 不为空时，commit 以 git worktree 的形式检出到该目录下并缓存，
+	// 之后再次分析同一个 commit 时可以直接复用
+	worktreeDir string
+}
+
+// openGitRepo 打开 dir 所在的 git 仓库，dir 可以是仓库的子目录
+func openGitRepo(dir string) (*gitRepo, error) {
+	r := &gitRepo{dir: dir}
+	root, err := r.lines("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	if len(root) == 0 {
+		return nil, fmt.Errorf("%s is not in a git repository", dir)
+	}
+	r.root = root[0]
+	// 位于仓库根目录时，--show-prefix 输出空行
+	prefix, err := r.lines("rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	if len(prefix) > 0 {
+		r.prefix = prefix[0]
+	}
+	return r, nil
+}
+
+// export 导出指定版本的代码到 dir，返回导出后项目根目录所在的位置。
+// 工作区的代码直接从项目目录中读取，不需要导出
+func (r *gitRepo) export(revision, dir string) (string, error) {
+	var err error
+	switch revision {
+	case RevisionWorktree:
+		return r.dir, nil
+	case RevisionIndex:
+		err = r.exportIndex(dir)
+	default:
+		if r.worktreeDir != "" {
+			dir, err = r.checkoutWorktree(revision)
+		} else {
+			err = r.exportCommit(revision, dir)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	// 导出的是整个仓库，项目可能位于仓库的子目录中
+	return filepath.Join(dir, r.prefix), nil
+}
+
+// exportCommit 使用 git archive 将指定 commit 的整个仓库导出到 dir，
+// 归档以流的方式边读取边解压，不会将整个归档读入内存
+func (r *gitRepo) exportCommit(commit, dir string) error {
+	// 确保目标目录存在
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", dir, err)
+	}
+
+	keep := r.keep
+	if keep != nil {
+		dirs, err := r.goPackageDirs(commit)
+		if err != nil {
+			return err
+		}
+		keep = func(name string) bool {
+			if _, ok := dirs[path.Dir(name)]; ok {
+				return true
+			}
+			return r.keep(name)
+		}
+	}
+
+	// 在子目录中执行 git archive 只会导出该子目录，因此在仓库根目录执行
+	cmd := exec.Command("git", "archive", "--format=tar", commit)
+	cmd.Dir = r.root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to archive commit: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to archive commit: %v", err)
+	}
+
+	// 解压到临时目录
+	if err := extractTar(stdout, dir, keep); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("failed to extract archive: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to archive commit: %v, %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// goPackageDirs 返回 commit 中包含 .go 文件的目录，路径相对于仓库根目录，与 git archive 中的路径格式相同
+func (r *gitRepo) goPackageDirs(commit string) (map[string]struct{}, error) {
+	// -z 使文件名不会被转义
+	cmd := exec.Command("git", "ls-tree", "-r", "-z", "--name-only", commit)
+	cmd.Dir = r.root
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %v", commit, err)
+	}
+	dirs := make(map[string]struct{})
+	for _, name := range strings.Split(string(output), "\x00") {
+		if strings.HasSuffix(name, ".go") {
+			dirs[path.Dir(name)] = struct{}{}
+		}
+	}
+	return dirs, nil
+}
+
+// extractTar 将 tar 归档解压到 dir 中，keep 不为 nil 时只解压 keep 返回 true 的文件，
+// keep 接收文件相对于仓库根目录的路径
+func extractTar(rd io.Reader, dir string, keep func(name string) bool) error {
+	tr := tar.NewReader(rd)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid file path in archive: %s", hdr.Name)
+		}
+		target := filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if keep != nil && !keep(hdr.Name) {
+				continue
+			}
+			if err := writeFile(target, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if keep != nil && !keep(hdr.Name) {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			// git archive 会写入记录 commit id 的 pax 全局头，忽略它和其他类型的条目
+		}
+	}
+}
+
+// writeFile 将 rd 中的内容写入文件 name，文件所在的目录不存在时会自动创建
+func writeFile(name string, rd io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rd); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// checkoutWorktree 将 commit 检出到 worktreeDir 下以 commit hash 命名的 git worktree 中，
+// 返回 worktree 的目录，目录已经存在时直接复用。
+// 检出完成后 worktree 会立即从仓库中注销，只保留检出的文件，不会在仓库的 .git/worktrees 中留下记录
+func (r *gitRepo) checkoutWorktree(commit string) (string, error) {
+	hash, err := r.resolveCommit(commit)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(r.worktreeDir, hash)
+	// commit 的内容不会变化，检出过的目录可以直接使用。
+	// 目录中还有 .git 时说明上次检出后没有完成注销，检出的文件可能不完整，需要重新检出
+	if _, err := os.Stat(dir); err == nil {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+			return dir, nil
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return "", fmt.Errorf("failed to remove incomplete worktree %s: %v", dir, err)
+		}
+	}
+	if err := os.MkdirAll(r.worktreeDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %v", r.worktreeDir, err)
+	}
+	// 缓存目录被删除后，仓库中仍然记录着原来的 worktree，需要先清理掉
+	if _, err := r.lines("worktree", "prune"); err != nil {
+		return "", err
+	}
+	// 分析不应该有副作用，因此禁用仓库中配置的 post-checkout 等 hooks
+	cmd := exec.Command("git", "-c", "core.hooksPath="+os.DevNull, "worktree", "add", "--detach", "--force", dir, hash)
+	cmd.Dir = r.root
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to add worktree: %v, %s", err, output)
+	}
+	// 删除 worktree 中指向仓库的 .git 文件后，prune 会清理掉仓库中关于它的记录
+	if err := os.Remove(filepath.Join(dir, ".git")); err != nil {
+		return "", fmt.Errorf("failed to detach worktree %s: %v", dir, err)
+	}
+	if _, err := r.lines("worktree", "prune"); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// resolveCommit 返回 commit 对应的完整 hash
+func (r *gitRepo) resolveCommit(commit string) (string, error) {
+	lines, err := r.lines("rev-parse", "--verify", commit+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("invalid commit %s: %v", commit, err)
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("invalid commit %s", commit)
+	}
+	return lines[0], nil
+}
+
+// exportIndex 将暂存区中的整个仓库导出到 dir
+func (r *gitRepo) exportIndex(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", dir, err)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	// --prefix 需要以路径分隔符结尾，否则会被当作文件名前缀
+	cmd := exec.Command("git", "checkout-index", "--all", "--force", "--prefix="+dir+string(filepath.Separator))
+	cmd.Dir = r.root
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to checkout index: %v, %s", err, output)
+	}
+	return nil
+}
+
+// changedFiles 返回两个版本之间发生变更的文件列表，路径相对于项目根目录，
+// 项目根目录之外的文件不会被返回
+func (r *gitRepo) changedFiles(oldCommit, newCommit string) ([]string, error) {
+	// --no-renames 使重命名的文件同时以旧路径和新路径出现
+	switch newCommit {
+	case RevisionWorktree:
+		files, err := r.lines("diff", "--name-only", "--no-renames", "--relative", oldCommit)
+		if err != nil {
+			return nil, err
+		}
+		// 未跟踪的文件同样属于工作区的变更
+		untracked, err := r.lines("ls-files", "--others", "--exclude-standard")
+		if err != nil {
+			return nil, err
+		}
+		return append(files, untracked...), nil
+	case RevisionIndex:
+		return r.lines("diff", "--name-only", "--no-renames", "--relative", "--cached", oldCommit)
+	default:
+		return r.lines("diff", "--name-only", "--no-renames", "--relative", oldCommit, newCommit)
+	}
+}
+
+// mergeBase 返回 oldCommit 与 newCommit 的共同祖先，
+// newCommit 为工作区或暂存区时，使用 HEAD 计算共同祖先
+func (r *gitRepo) mergeBase(oldCommit, newCommit string) (string, error) {
+	if newCommit == RevisionWorktree || newCommit == RevisionIndex {
+		newCommit = "HEAD"
+	}
+	lines, err := r.lines("merge-base", oldCommit, newCommit)
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("no merge base between %s and %s", oldCommit, newCommit)
+	}
+	return lines[0], nil
+}
+
+// lines 在项目目录下执行 git 命令，并按行返回非空的输出
+func (r *gitRepo) lines(args ...string) ([]string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run git %s: %v", args[0], err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
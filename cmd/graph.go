@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/bootun/veronica/parser"
+	"github.com/spf13/cobra"
+)
+
+// veronica graph 支持的输出格式
+const (
+	// GraphFormatDOT 输出 Graphviz DOT 格式，可以使用 dot -Tsvg 渲染
+	GraphFormatDOT = "dot"
+	// GraphFormatMermaid 输出 Mermaid flowchart，可以直接嵌入 Markdown
+	GraphFormatMermaid = "mermaid"
+	// GraphFormatJSON 输出 JSON 格式的邻接表
+	GraphFormatJSON = "json"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "export the dependency graph",
+	Run: func(cmd *cobra.Command, args []string) {
+		if graphTarget != "" && graphService != "" {
+			log.Fatalf("--target and --service cannot be used together")
+		}
+		var roots []string
+		if graphService != "" {
+			project, err := parser.NewProject(repo)
+			if err != nil {
+				log.Fatalf("failed to parse project: %v", err)
+			}
+			svc, ok := findService(project.Services, graphService)
+			if !ok {
+				log.Fatalf("service %s is not defined in veronica.yaml", graphService)
+			}
+			roots = append(roots, svc.Entrypoint)
+		}
+
+		pkgs, err := parser.LoadPackages(repo)
+		if err != nil {
+			log.Fatalf("load packages: %s", err)
+		}
+		dependencyInfo, err := parser.BuildDependency(pkgs)
+		if err != nil {
+			log.Fatalf("build dependency: %s", err)
+		}
+		if graphTarget != "" {
+			roots = append(roots, resolveTarget(dependencyInfo, graphTarget))
+		}
+		graph, err := dependencyInfo.Subgraph(roots, parser.Direction(graphDirection), graphDepth)
+		if err != nil {
+			log.Fatalf("get subgraph: %s", err)
+		}
+		if collapse {
+			graph = dependencyInfo.CollapsePackages(graph)
+		}
+
+		switch graphFormat {
+		case GraphFormatDOT:
+			err = parser.WriteDOT(os.Stdout, graph)
+		case GraphFormatMermaid:
+			err = parser.WriteMermaid(os.Stdout, graph)
+		case GraphFormatJSON:
+			err = parser.WriteJSON(os.Stdout, graph)
+		default:
+			log.Fatalf("invalid format: %s", graphFormat)
+		}
+		if err != nil {
+			log.Fatalf("failed to write graph: %v", err)
+		}
+	},
+}
+
+var (
+	graphFormat string
+	// graphTarget 和 graphService 不为空时，只输出以它们为起点的子图
+	graphTarget    string
+	graphService   string
+	graphDirection string
+	graphDepth     int
+	// collapse 是否将声明合并为包，输出包之间的依赖关系
+	collapse bool
+)
+
+func init() {
+	graphCmd.Flags().StringVarP(&repo, "repo", "r", ".", "repo path")
+	graphCmd.Flags().StringVarP(&graphFormat, "format", "f", GraphFormatDOT, "output format (dot, mermaid, json)")
+	graphCmd.Flags().StringVarP(&graphTarget, "target", "t", "", "only export the subgraph rooted at the target")
+	graphCmd.Flags().StringVarP(&graphService, "service", "s", "", "only export the subgraph rooted at the entrypoint of the service in veronica.yaml")
+	graphCmd.Flags().StringVar(&graphDirection, "direction", string(parser.DirectionForward), "direction of the subgraph (forward: what the root depends on, reverse: what depends on the root)")
+	graphCmd.Flags().IntVar(&graphDepth, "depth", 0, "maximum number of steps from the root, 0 means unlimited")
+	graphCmd.Flags().BoolVar(&collapse, "package", false, "collapse declarations into packages")
+}
+
+// findService 根据名称查找 veronica.yaml 中的服务
+func findService(services map[string]parser.Service, name string) (parser.Service, bool) {
+	for _, svc := range services {
+		if svc.Name == name {
+			return svc, true
+		}
+	}
+	return parser.Service{}, false
+}
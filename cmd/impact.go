@@ -4,20 +4,21 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/bootun/veronica/astdiff"
 	"github.com/bootun/veronica/parser"
 	"github.com/spf13/cobra"
+	"golang.org/x/tools/go/packages"
 )
 
 var impactCmd = &cobra.Command{
 	Use:   "impact",
 	Short: "impact",
 	Run: func(cmd *cobra.Command, args []string) {
-		if oldCommit == "" {
+		if oldCommit == "" || oldCommit == RevisionWorktree || oldCommit == RevisionIndex {
 			cmd.Usage()
 			os.Exit(1)
 		}
@@ -34,6 +35,19 @@ var (
 	newCommit string
 	repo      string // 仓库路径
 	scope     string // 报告的变更范围(all, service)
+	output    string // 报告的输出格式(text, json)
+	explain   bool   // 是否解释服务受到影响的原因
+	mergeBase bool   // 是否与 old 和 new 的共同祖先进行比较
+	goOnly    bool   // 导出时是否只导出 Go 相关的文件
+	export    string // 导出 commit 的方式(archive, worktree)
+	cacheDir  string // 缓存目录
+	// 是否只加载变更涉及的包，而不是整个项目
+	incremental bool
+	// 是否缓存 commit 的依赖图
+	cacheDeps bool
+	// 只有函数体发生变化时，是否只沿着调用关系传播影响，
+	// 只有结构体字段发生变化时，是否只影响读写了这些字段的代码
+	precise bool
 )
 
 const (
@@ -41,164 +55,506 @@ const (
 	ScopeService = "service"
 )
 
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+const (
+	// ExportArchive 每次运行时使用 git archive 将 commit 导出到临时目录
+	ExportArchive = "archive"
+	// ExportWorktree 使用 git worktree 将 commit 检出到缓存目录中，并在之后的运行中复用，
+	// 检出时不会执行仓库的 hooks，检出后 worktree 会从仓库中注销
+	ExportWorktree = "worktree"
+)
+
+// 可以作为 --new 的特殊版本
+const (
+	// RevisionWorktree 表示工作区中的代码，包括未提交和未跟踪的文件
+	RevisionWorktree = "WORKTREE"
+	// RevisionIndex 表示暂存区(git add 之后)中的代码
+	RevisionIndex = "INDEX"
+)
+
 func init() {
 	impactCmd.Flags().StringVarP(&oldCommit, "old", "o", "", "old commit")
-	impactCmd.Flags().StringVarP(&newCommit, "new", "n", "", "new commit")
+	impactCmd.Flags().StringVarP(&newCommit, "new", "n", RevisionWorktree, "new commit, or WORKTREE for the working tree, INDEX for the staged changes")
 	impactCmd.Flags().StringVarP(&repo, "repo", "r", ".", "repo path")
 	impactCmd.Flags().StringVarP(&scope, "scope", "s", ScopeAll, "report scope, options: all, service")
+	impactCmd.Flags().StringVar(&output, "output", OutputText, "report format, options: text, json")
+	impactCmd.Flags().BoolVar(&explain, "explain", false, "explain why each service is affected")
+	impactCmd.Flags().StringVar(&export, "export", ExportArchive, "how to export commits, options: archive, worktree (checked out into the cache dir without running hooks and reused across runs)")
+	impactCmd.Flags().StringVar(&cacheDir, "cache-dir", defaultCacheDir(), "cache directory")
+	impactCmd.Flags().BoolVar(&goOnly, "go-only", false, "only export Go files, go.mod/go.sum, other files in directories containing Go files and files matching hooks from commits")
+	impactCmd.Flags().BoolVar(&incremental, "incremental", false, "only load packages containing changed files and the packages importing them")
+	impactCmd.Flags().BoolVar(&cacheDeps, "cache", false, "cache dependency graphs of commits in --cache-dir and reuse them in later runs")
+	impactCmd.Flags().BoolVar(&precise, "precise", false, "propagate body-only changes along calls only and struct field changes to code that reads or writes the fields only")
+	impactCmd.Flags().BoolVar(&mergeBase, "merge-base", false, "compare against the merge base of old and new, same as --old=<old>...<new>")
 }
 
 func Impact(oldCommit, newCommit string) {
 	log.SetFlags(log.Lshortfile | log.LstdFlags)
+	// 所有的 git 操作都在 --repo 指定的项目目录下进行
+	git, err := openGitRepo(repo)
+	if err != nil {
+		log.Fatalf("open git repository: %s", err)
+	}
+	// 三点语法 old...new，与 git diff 相同，表示比较 new 与两者的共同祖先
+	if base, head, ok := strings.Cut(oldCommit, "..."); ok {
+		oldCommit = base
+		if head != "" {
+			newCommit = head
+		}
+		mergeBase = true
+	}
+	if mergeBase {
+		base, err := git.mergeBase(oldCommit, newCommit)
+		if err != nil {
+			log.Fatalf("failed to resolve merge base: %v", err)
+		}
+		oldCommit = base
+	}
 	project, err := parser.NewProject(repo)
 	if err != nil {
 		log.Fatalf("load project: %s", err)
 	}
+	diffMode, err := astdiff.ParseMode(project.DiffMode)
+	if err != nil {
+		log.Fatalf("load project: %s", err)
+	}
+	switch export {
+	case ExportArchive:
+	case ExportWorktree:
+		git.worktreeDir = filepath.Join(cacheDir, "worktrees")
+	default:
+		log.Fatalf("invalid export: %s", export)
+	}
+	if goOnly {
+		// 只导出分析所需的文件，减少大仓库导出时的磁盘占用和耗时
+		git.keep = func(name string) bool {
+			if isGoFile(name) {
+				return true
+			}
+			// hooks 中的路径相对于项目根目录
+			file, ok := strings.CutPrefix(name, git.prefix)
+			if !ok {
+				return false
+			}
+			if _, hooked := project.MatchGlobalHooks([]string{file}); hooked {
+				return true
+			}
+			for _, svc := range project.Services {
+				if svc.MatchHooks(file) {
+					return true
+				}
+			}
+			return false
+		}
+	}
 	// 创建临时目录
 	tmpDir, err := os.MkdirTemp("", "veronica-astdiff-*")
 	if err != nil {
 		log.Fatalf("Failed to create temp directory: %v", err)
 	}
 	defer os.RemoveAll(tmpDir)
-	oldDir := filepath.Join(tmpDir, "old")
-	newDir := filepath.Join(tmpDir, "new")
-	// 使用 git archive 导出指定版本
-	if err := exportCommit(oldCommit, oldDir); err != nil {
-		log.Fatalf("failed to export commit: %v", err)
+	// 导出指定版本，oldDir 和 newDir 为导出后项目根目录所在的位置
+	oldDir, err := git.export(oldCommit, filepath.Join(tmpDir, "old"))
+	if err != nil {
+		log.Fatalf("failed to export %s: %v", oldCommit, err)
+	}
+	newDir, err := git.export(newCommit, filepath.Join(tmpDir, "new"))
+	if err != nil {
+		log.Fatalf("failed to export %s: %v", newCommit, err)
+	}
+
+	// 获取两个版本之间变更的文件，用于匹配 service 的 hooks 以及确定需要加载的包
+	files, err := git.changedFiles(oldCommit, newCommit)
+	if err != nil {
+		log.Fatalf("failed to get changed files: %v", err)
 	}
-	if err := exportCommit(newCommit, newDir); err != nil {
-		log.Fatalf("failed to export commit: %v", err)
+
+	// 只有加载了整个项目时构建的依赖图才是完整的，才能被缓存
+	complete := !incremental || modulesChanged(files)
+	var cache *dependencyCache
+	if cacheDeps {
+		cache = &dependencyCache{dir: filepath.Join(cacheDir, "dependencies"), repo: git}
 	}
 
-	// 加载包信息
-	oldPkgs, err := parser.LoadPackages(oldDir)
+	// 加载包信息，old 的依赖图已经缓存时只需要解析语法树
+	var oldDeps *parser.DependencyInfo
+	oldCached := false
+	if cache != nil {
+		oldDeps, oldCached = cache.load(oldCommit)
+	}
+	oldPkgs, err := loadPackages(oldDir, files, oldCached)
 	if err != nil {
 		log.Fatalf("failed to load packages: %v", err)
 	}
-	newPkgs, err := parser.LoadPackages(newDir)
+	newPkgs, err := loadPackages(newDir, files, false)
 	if err != nil {
 		log.Fatalf("failed to load packages: %v", err)
 	}
 
 	// 分析AST差异
-	diff, err := astdiff.LoadDiff(oldPkgs, newPkgs)
+	diff, err := astdiff.LoadDiff(oldPkgs, newPkgs, diffMode)
 	if err != nil {
 		log.Fatalf("failed to load diff: %v", err)
 	}
-	oldDeps, err := parser.BuildDependency(oldPkgs)
-	if err != nil {
-		log.Fatalf("failed to build dependency: %v", err)
+	if !oldCached {
+		oldDeps, err = parser.BuildDependency(oldPkgs)
+		if err != nil {
+			log.Fatalf("failed to build dependency: %v", err)
+		}
+		if cache != nil && complete {
+			if err := cache.save(oldCommit, oldDeps); err != nil {
+				log.Printf("failed to cache dependency of %s: %v", oldCommit, err)
+			}
+		}
 	}
 	newDeps, err := parser.BuildDependency(newPkgs)
 	if err != nil {
 		log.Fatalf("failed to build dependency: %v", err)
 	}
+	// new 是 commit 时同样缓存，之后与它进行比较时可以直接使用
+	if cache != nil && complete && newCommit != RevisionWorktree && newCommit != RevisionIndex {
+		if err := cache.save(newCommit, newDeps); err != nil {
+			log.Printf("failed to cache dependency of %s: %v", newCommit, err)
+		}
+	}
+	// 依赖的外部模块版本发生变化时，引用了这些模块的顶层声明也视为修改
+	moduleChanges, err := getModuleChanges(goModPath(oldDir, project.GoMod), goModPath(newDir, project.GoMod), diff.Objects, newDeps)
+	if err != nil {
+		log.Fatalf("failed to get module changes: %v", err)
+	}
+	diff.Changes = mergeChanges(diff.Changes, moduleChanges)
+
+	// 受影响的服务
+	var effectedServices []parser.Service
+	hookedFile, globalHooked := project.MatchGlobalHooks(files)
+	if globalHooked {
+		// 命中了全局 hooks，所有的服务都受到影响
+		for _, svc := range project.Services {
+			effectedServices = append(effectedServices, svc)
+		}
+	} else {
+		effectedServices = getEffectedServices(project.Services, project.Module.Name, oldDeps, newDeps, diff.Changes, files)
+	}
+	sort.Slice(effectedServices, func(i, j int) bool {
+		return effectedServices[i].Name < effectedServices[j].Name
+	})
+	// 服务受到影响的原因, key: 服务名
+	var explanations map[string]string
+	if explain {
+		explanations = make(map[string]string, len(effectedServices))
+		for _, svc := range effectedServices {
+			if globalHooked {
+				explanations[svc.Name] = fmt.Sprintf("%s matches global hooks", hookedFile)
+				continue
+			}
+			explanations[svc.Name] = explainService(svc, project.Module.Name, oldDeps, newDeps, diff.Changes, files)
+		}
+	}
+
+	switch output {
+	case OutputJSON:
+		report := newImpactReport(diff.Changes, oldDeps, newDeps, effectedServices, explanations)
+		if err := report.Write(os.Stdout); err != nil {
+			log.Fatalf("failed to write report: %v", err)
+		}
+	case OutputText:
+		printTextReport(diff.Changes, oldDeps, newDeps, effectedServices, explanations)
+	default:
+		log.Fatalf("invalid output: %s", output)
+	}
+}
 
+// printTextReport 以文本的形式输出报告
+func printTextReport(changes []astdiff.Change, oldDeps, newDeps *parser.DependencyInfo, effectedServices []parser.Service, explanations map[string]string) {
 	switch scope {
 	case ScopeAll:
 		// 报告所有影响
-		for _, change := range diff.Changes {
+		for _, change := range changes {
+			deps := getChangeDependencies(change, oldDeps, newDeps)
 			switch change.Type {
 			case astdiff.ChangeTypeAdded:
-				deps, err := newDeps.GetDependency(change.ObjectID)
-				if err != nil {
-					log.Fatalf("failed to get dependency: %v", err)
-				}
 				fmt.Printf("add %s in %s, dependencies:\n", change.Object, change.File)
-				for i, dep := range deps {
-					fmt.Printf("  %d. %s\n", i+1, dep)
-				}
 			case astdiff.ChangeTypeRemoved:
-				deps, err := oldDeps.GetDependency(change.ObjectID)
-				if err != nil {
-					log.Fatalf("failed to get dependency: %v", err)
-				}
 				fmt.Printf("remove %s in %s, dependencies:\n", change.Object, change.File)
-				for i, dep := range deps {
-					fmt.Printf("  %d. %s\n", i+1, dep)
-				}
 			case astdiff.ChangeTypeModified:
-				deps, err := newDeps.GetDependency(change.ObjectID)
-				if err != nil {
-					log.Fatalf("failed to get dependency: %v", err)
-				}
-				fmt.Printf("modify %s in %s, dependencies:\n", change.Object, change.File)
-				for i, dep := range deps {
-					fmt.Printf("  %d. %s\n", i+1, dep)
-				}
+				fmt.Printf("modify %s in %s%s, dependencies:\n", change.Object, change.File, describeDetail(change.Detail))
+			case astdiff.ChangeTypeRenamed:
+				fmt.Printf("rename %s to %s in %s, dependencies:\n", change.OldObject, change.Object, change.File)
+			case astdiff.ChangeTypeMoved:
+				fmt.Printf("move %s from %s to %s\n", change.Object, change.OldFile, change.File)
+			}
+			for i, dep := range deps {
+				fmt.Printf("  %d. %s\n", i+1, dep)
 			}
 		}
 	case ScopeService:
 		// 只报告受影响的服务
-		effectedServices := getEffectedServices(project.Services, oldDeps, newDeps, diff.Changes)
-		// fmt.Printf("受影响的entrypoint有:\n")
 		for _, service := range effectedServices {
-			fmt.Printf("%s\n", service)
+			fmt.Printf("%s\n", service.Name)
+			if explanation, ok := explanations[service.Name]; ok {
+				fmt.Printf("  %s\n", explanation)
+			}
 		}
 	default:
 		log.Fatalf("invalid scope: %s", scope)
 	}
 }
 
-func exportCommit(commit, dir string) error {
-	// 确保目标目录存在
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %v", dir, err)
+// describeDetail 返回修改种类的文字描述，如 " (signature, potentially breaking)"
+func describeDetail(detail astdiff.ChangeDetail) string {
+	if detail == "" {
+		return ""
+	}
+	if detail.Breaking() {
+		return fmt.Sprintf(" (%s, potentially breaking)", detail)
 	}
+	return fmt.Sprintf(" (%s)", detail)
+}
+
+// defaultCacheDir 返回默认的缓存目录，通常是 ~/.cache/veronica
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "veronica")
+}
 
-	// 使用 git archive 导出指定版本
-	cmd := exec.Command("git", "archive", "--format=tar", commit)
-	output, err := cmd.Output()
+// loadPackages 加载 dir 中的包，syntaxOnly 为 true 时只解析语法树。
+// 指定了 --incremental 时只加载受 files 影响的包，
+// 模块的依赖发生变化时，任何包都可能受到影响，此时仍然加载所有的包
+func loadPackages(dir string, files []string, syntaxOnly bool) ([]*packages.Package, error) {
+	load := parser.LoadPackages
+	if syntaxOnly {
+		load = parser.LoadSyntax
+	}
+	if !incremental || modulesChanged(files) {
+		return load(dir)
+	}
+	ids, err := parser.AffectedPackages(dir, files)
 	if err != nil {
-		return fmt.Errorf("failed to archive commit: %v", err)
+		return nil, err
+	}
+	// 没有 Go 代码发生变化
+	if len(ids) == 0 {
+		return nil, nil
 	}
+	return load(dir, ids...)
+}
+
+// modulesChanged 判断变更的文件中是否包含模块的依赖信息
+func modulesChanged(files []string) bool {
+	for _, file := range files {
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			return true
+		}
+	}
+	return false
+}
+
+// isGoFile 判断文件是否是加载 Go 包时需要的文件
+func isGoFile(name string) bool {
+	switch filepath.Base(name) {
+	case "go.mod", "go.sum", "go.work", "go.work.sum":
+		return true
+	}
+	return filepath.Ext(name) == ".go"
+}
 
-	// 解压到临时目录
-	cmd = exec.Command("tar", "-xf", "-")
-	cmd.Dir = dir
-	cmd.Stdin = strings.NewReader(string(output))
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to extract archive: %v", err)
+// goModPath 返回导出后的项目根目录 root 中 go.mod 的位置，goMod 为配置文件中的 go.mod 选项，
+// 与 parser.NewProject 相同，相对路径相对于项目根目录，绝对路径不随导出的版本变化
+func goModPath(root, goMod string) string {
+	if goMod == "" {
+		return filepath.Join(root, "go.mod")
 	}
+	if filepath.IsAbs(goMod) {
+		return goMod
+	}
+	return filepath.Join(root, goMod)
+}
 
-	return nil
+// getModuleChanges 比较新旧版本的 go.mod，返回引用了版本发生变化的外部模块的顶层声明
+func getModuleChanges(oldGoMod, newGoMod string, newObjects map[string]astdiff.Object, newDeps *parser.DependencyInfo) ([]astdiff.Change, error) {
+	oldMod, err := parser.ParseGoModuleInfo(oldGoMod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse old go.mod: %v", err)
+	}
+	newMod, err := parser.ParseGoModuleInfo(newGoMod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse new go.mod: %v", err)
+	}
+	changed := make(map[string]struct{})
+	for _, module := range parser.ChangedModules(oldMod, newMod) {
+		changed[module] = struct{}{}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	ids := newDeps.GetExternalReferrers(func(pkgPath string) bool {
+		_, ok := changed[newMod.ModuleOf(pkgPath)]
+		return ok
+	})
+	return astdiff.MarkModified(newObjects, ids), nil
 }
 
-func getEffectedServices(services map[string]parser.Service, oldDeps, newDeps *parser.DependencyInfo, changes []astdiff.Change) []string {
-	effecteds := make(map[string]bool)
+// mergeChanges 将 extra 合并到 changes 中，已经存在的对象不会重复添加
+func mergeChanges(changes, extra []astdiff.Change) []astdiff.Change {
+	exists := make(map[string]struct{}, len(changes))
 	for _, change := range changes {
+		exists[change.ObjectID] = struct{}{}
+	}
+	for _, change := range extra {
+		if _, ok := exists[change.ObjectID]; ok {
+			continue
+		}
+		exists[change.ObjectID] = struct{}{}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// getChangeDependencies 获取受变更影响的节点，新增和修改的对象在新版本中查找，移除的对象在旧版本中查找，
+// 重命名的对象同时包括旧版本中依赖旧名称的节点和新版本中依赖新名称的节点，
+// 只是移动到其他文件的对象不会影响任何节点
+func getChangeDependencies(change astdiff.Change, oldDeps, newDeps *parser.DependencyInfo) []string {
+	var (
+		deps []string
+		err  error
+	)
+	switch change.Type {
+	case astdiff.ChangeTypeAdded:
+		deps, err = newDeps.GetDependency(change.ObjectID)
+	case astdiff.ChangeTypeRemoved:
+		deps, err = oldDeps.GetDependency(change.ObjectID)
+	case astdiff.ChangeTypeModified:
+		if fieldsOnly(change) {
+			// 影响由每个发生变化的字段单独计算
+			break
+		}
+		if callOnly(change) {
+			deps, err = newDeps.GetCallDependency(change.ObjectID)
+		} else {
+			deps, err = newDeps.GetDependency(change.ObjectID)
+		}
+	case astdiff.ChangeTypeRenamed:
+		deps, err = oldDeps.GetDependency(change.OldObjectID)
+		if err != nil {
+			break
+		}
+		var newer []string
+		newer, err = newDeps.GetDependency(change.ObjectID)
+		deps = mergeDependencies(deps, newer)
+	}
+	if err != nil {
+		log.Fatalf("failed to get dependency: %v", err)
+	}
+	return deps
+}
+
+// callOnly 判断变更的影响是否只沿着调用关系传播，
+// 指定了 --precise 时，只修改了函数体的函数只会影响直接或间接调用了它的代码
+func callOnly(change astdiff.Change) bool {
+	return precise && change.Type == astdiff.ChangeTypeModified &&
+		(change.Detail == astdiff.DetailBody || change.Detail == astdiff.DetailReceiver)
+}
+
+// fieldsOnly 判断变更是否只修改了结构体的字段，
+// 指定了 --precise 时，这样的结构体只会影响读写了发生变化的字段的代码
+func fieldsOnly(change astdiff.Change) bool {
+	return precise && change.Type == astdiff.ChangeTypeModified && change.Detail == astdiff.DetailFields
+}
+
+// mergeDependencies 合并两组依赖节点并去重
+func mergeDependencies(a, b []string) []string {
+	exists := make(map[string]struct{}, len(a))
+	for _, id := range a {
+		exists[id] = struct{}{}
+	}
+	for _, id := range b {
+		if _, ok := exists[id]; !ok {
+			exists[id] = struct{}{}
+			a = append(a, id)
+		}
+	}
+	return a
+}
+
+// explainService 解释服务受到影响的原因，优先返回一条从变更对象到服务 entrypoint 的最短依赖路径，
+// 如 "TagBaseEnt -> (*tagRepo).GetOneTagById -> (*PlayletServer).GetPlayletInfo"，
+// 没有依赖路径时返回命中了服务 hooks 的文件
+func explainService(svc parser.Service, moduleName string, oldDeps, newDeps *parser.DependencyInfo, changes []astdiff.Change, files []string) string {
+	var shortest []string
+	for _, change := range changes {
+		file := strings.TrimPrefix(change.File, moduleName+"/")
+		if change.Type == astdiff.ChangeTypeMoved || fieldsOnly(change) || svc.MatchIgnores(file) {
+			continue
+		}
+		var paths [][]string
 		switch change.Type {
-		case astdiff.ChangeTypeAdded:
-			deps, err := newDeps.GetDependency(change.ObjectID)
-			if err != nil {
-				log.Fatalf("failed to get dependency: %v", err)
-			}
-			for _, dep := range deps {
-				effecteds[dep] = true
-			}
 		case astdiff.ChangeTypeRemoved:
-			deps, err := oldDeps.GetDependency(change.ObjectID)
-			if err != nil {
-				log.Fatalf("failed to get dependency: %v", err)
+			// 移除的对象只存在于旧版本中
+			paths = append(paths, oldDeps.GetPath(change.ObjectID, svc.Entrypoint))
+		case astdiff.ChangeTypeRenamed:
+			paths = append(paths, oldDeps.GetPath(change.OldObjectID, svc.Entrypoint), newDeps.GetPath(change.ObjectID, svc.Entrypoint))
+		default:
+			if callOnly(change) {
+				paths = append(paths, newDeps.GetCallPath(change.ObjectID, svc.Entrypoint))
+			} else {
+				paths = append(paths, newDeps.GetPath(change.ObjectID, svc.Entrypoint))
 			}
-			for _, dep := range deps {
-				effecteds[dep] = true
-			}
-		case astdiff.ChangeTypeModified:
-			deps, err := newDeps.GetDependency(change.ObjectID)
-			if err != nil {
-				log.Fatalf("failed to get dependency: %v", err)
+		}
+		for _, path := range paths {
+			if path != nil && (shortest == nil || len(path) < len(shortest)) {
+				shortest = path
 			}
-			for _, dep := range deps {
-				effecteds[dep] = true
+		}
+	}
+	if shortest != nil {
+		names := make([]string, 0, len(shortest))
+		for _, id := range shortest {
+			names = append(names, id[strings.LastIndex(id, ":")+1:])
+		}
+		return strings.Join(names, " -> ")
+	}
+	for _, file := range files {
+		if svc.MatchHooks(file) {
+			return fmt.Sprintf("%s matches hooks", file)
+		}
+	}
+	return ""
+}
+
+func getEffectedServices(services map[string]parser.Service, moduleName string, oldDeps, newDeps *parser.DependencyInfo, changes []astdiff.Change, files []string) []parser.Service {
+	effecteds := make(map[string]parser.Service)
+	for _, change := range changes {
+		deps := getChangeDependencies(change, oldDeps, newDeps)
+		// 变更所在的文件，相对于项目根目录，用于匹配 service 的 ignores
+		file := strings.TrimPrefix(change.File, moduleName+"/")
+		for _, dep := range deps {
+			svc, ok := services[dep]
+			if !ok || svc.MatchIgnores(file) {
+				continue
 			}
+			effecteds[svc.Name] = svc
 		}
 	}
-	effectedServices := make([]string, 0, len(effecteds))
-	for effected := range effecteds {
-		if svc, ok := services[effected]; ok {
-			effectedServices = append(effectedServices, svc.Name)
+	// 变更的文件命中了 service 的 hooks
+	for _, svc := range services {
+		for _, file := range files {
+			if svc.MatchHooks(file) {
+				effecteds[svc.Name] = svc
+				break
+			}
 		}
 	}
+	effectedServices := make([]parser.Service, 0, len(effecteds))
+	for _, svc := range effecteds {
+		effectedServices = append(effectedServices, svc)
+	}
 	return effectedServices
 }
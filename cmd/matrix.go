@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/bootun/veronica/parser"
+	"github.com/spf13/cobra"
+)
+
+var matrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "show which packages each service reaches",
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgs, err := parser.LoadPackages(repo)
+		if err != nil {
+			log.Fatalf("load packages: %s", err)
+		}
+		dependencyInfo, err := parser.BuildDependency(pkgs)
+		if err != nil {
+			log.Fatalf("build dependency: %s", err)
+		}
+		if packageEdges {
+			printPackageEdges(dependencyInfo.PackageGraph())
+			return
+		}
+
+		project, err := parser.NewProject(repo)
+		if err != nil {
+			log.Fatalf("failed to parse project: %v", err)
+		}
+		services := make([]parser.Service, 0, len(project.Services))
+		for _, svc := range project.Services {
+			services = append(services, svc)
+		}
+		sort.Slice(services, func(i, j int) bool {
+			return services[i].Name < services[j].Name
+		})
+		// key: 包路径, value: 可以到达该包的服务名称
+		reachedBy := make(map[string]map[string]struct{})
+		for _, svc := range services {
+			reachable, err := dependencyInfo.ReachablePackages(svc.Entrypoint)
+			if err != nil {
+				log.Fatalf("service %s: %v", svc.Name, err)
+			}
+			for _, pkg := range reachable {
+				if _, ok := reachedBy[pkg]; !ok {
+					reachedBy[pkg] = make(map[string]struct{})
+				}
+				reachedBy[pkg][svc.Name] = struct{}{}
+			}
+		}
+		printServiceMatrix(services, reachedBy)
+	},
+}
+
+var (
+	// allPackages 是否输出所有被服务用到的包，默认只输出被多个服务共享的包
+	allPackages bool
+	// packageEdges 是否输出包之间带权重的依赖关系，而不是服务与包的矩阵
+	packageEdges bool
+)
+
+func init() {
+	matrixCmd.Flags().StringVarP(&repo, "repo", "r", ".", "repo path")
+	matrixCmd.Flags().BoolVar(&allPackages, "all", false, "show all packages reached by any service, not only the shared ones")
+	matrixCmd.Flags().BoolVar(&packageEdges, "packages", false, "show package-to-package dependencies weighted by the number of declaration dependencies")
+}
+
+// printServiceMatrix 以表格的形式输出每个服务用到了哪些包，行为包，列为服务
+func printServiceMatrix(services []parser.Service, reachedBy map[string]map[string]struct{}) {
+	pkgs := make([]string, 0, len(reachedBy))
+	for pkg, svcs := range reachedBy {
+		if allPackages || len(svcs) > 1 {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Strings(pkgs)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "PACKAGE")
+	for _, svc := range services {
+		fmt.Fprintf(w, "\t%s", svc.Name)
+	}
+	fmt.Fprintln(w)
+	for _, pkg := range pkgs {
+		fmt.Fprint(w, pkg)
+		for _, svc := range services {
+			if _, ok := reachedBy[pkg][svc.Name]; ok {
+				fmt.Fprint(w, "\tx")
+			} else {
+				fmt.Fprint(w, "\t.")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+// printPackageEdges 按权重从大到小输出包之间的依赖关系
+func printPackageEdges(graph parser.WeightedGraph) {
+	type edge struct {
+		from, to string
+		weight   int
+	}
+	var edges []edge
+	for from, deps := range graph {
+		for to, weight := range deps {
+			edges = append(edges, edge{from: from, to: to, weight: weight})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].weight != edges[j].weight {
+			return edges[i].weight > edges[j].weight
+		}
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		return edges[i].to < edges[j].to
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tDEPENDS ON\tWEIGHT")
+	for _, e := range edges {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", e.from, e.to, e.weight)
+	}
+	w.Flush()
+}
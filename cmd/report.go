@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/bootun/veronica/astdiff"
+	"github.com/bootun/veronica/parser"
+)
+
+// ReportSchemaVersion 是 JSON 报告的格式版本，报告的格式发生不兼容的变化时递增。
+// 变更的 type 新增了取值(如 renamed)也视为不兼容的变化，因为使用方通常会根据 type 分别处理；
+// 新增可选的字段，以及 object_type 和 detail 新增取值视为兼容的变化
+const ReportSchemaVersion = 3
+
+// ImpactReport 是 veronica impact 以 JSON 格式输出的报告
+type ImpactReport struct {
+	SchemaVersion int             `json:"schema_version"`
+	Changes       []ChangeReport  `json:"changes"`
+	Services      []ServiceReport `json:"services"`
+}
+
+// ChangeReport 描述一个顶层声明的变更及受其影响的声明
+type ChangeReport struct {
+	Type       astdiff.ChangeType `json:"type"`
+	Package    string             `json:"package"`
+	Object     string             `json:"object"`
+	ObjectType string             `json:"object_type"`
+	ObjectID   string             `json:"object_id"`
+	File       string             `json:"file"`
+	// OldFile 是对象在旧版本中所在的文件，只在对象所在的文件发生变化时输出
+	OldFile string `json:"old_file,omitempty"`
+	// OldObject 和 OldObjectID 是对象在旧版本中的名称和唯一标识符，只在对象被重命名时输出
+	OldObject   string `json:"old_object,omitempty"`
+	OldObjectID string `json:"old_object_id,omitempty"`
+	// Detail 是修改的具体种类，Breaking 表示该修改可能破坏依赖方，只在修改时输出
+	Detail   astdiff.ChangeDetail `json:"detail,omitempty"`
+	Breaking bool                 `json:"breaking,omitempty"`
+	// Dependents 是直接或间接依赖该对象的顶层声明
+	Dependents []string `json:"dependents"`
+}
+
+// ServiceReport 描述一个受影响的服务
+type ServiceReport struct {
+	Name       string `json:"name"`
+	Entrypoint string `json:"entrypoint"`
+	// Explain 解释服务受到影响的原因，只在指定了 --explain 时输出
+	Explain string `json:"explain,omitempty"`
+}
+
+func newImpactReport(changes []astdiff.Change, oldDeps, newDeps *parser.DependencyInfo, services []parser.Service, explanations map[string]string) *ImpactReport {
+	report := &ImpactReport{
+		SchemaVersion: ReportSchemaVersion,
+		Changes:       make([]ChangeReport, 0, len(changes)),
+		Services:      make([]ServiceReport, 0, len(services)),
+	}
+	for _, change := range changes {
+		deps := getChangeDependencies(change, oldDeps, newDeps)
+		if deps == nil {
+			deps = []string{}
+		}
+		sort.Strings(deps)
+		report.Changes = append(report.Changes, ChangeReport{
+			Type:        change.Type,
+			Package:     change.Package,
+			Object:      change.Object,
+			ObjectType:  change.ObjectType,
+			ObjectID:    change.ObjectID,
+			File:        change.File,
+			OldFile:     change.OldFile,
+			OldObject:   change.OldObject,
+			OldObjectID: change.OldObjectID,
+			Detail:      change.Detail,
+			Breaking:    change.Detail.Breaking(),
+			Dependents:  deps,
+		})
+	}
+	sort.Slice(report.Changes, func(i, j int) bool {
+		return report.Changes[i].ObjectID < report.Changes[j].ObjectID
+	})
+	for _, svc := range services {
+		report.Services = append(report.Services, ServiceReport{
+			Name:       svc.Name,
+			Entrypoint: svc.Entrypoint,
+			Explain:    explanations[svc.Name],
+		})
+	}
+	return report
+}
+
+// Write 将报告以 JSON 格式写入 w
+func (r *ImpactReport) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -12,6 +12,8 @@ func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(dependencyCmd)
 	rootCmd.AddCommand(impactCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(matrixCmd)
 }
 
 func Execute() error {
@@ -8,10 +8,20 @@ import (
 )
 
 type Config struct {
-	Version  string             `yaml:"version"`
+	Version  string              `yaml:"version"`
 	Services map[string]*Service `yaml:"services"`
-	GoMod    string             `yaml:"go.mod"`
-	Hooks    []string           `yaml:"hooks"`
+	GoMod    string              `yaml:"go.mod"`
+	// Hooks are file patterns, changes to matching files affect all services
+	Hooks []string `yaml:"hooks"`
+	// Diff controls how declarations of two versions are compared
+	Diff Diff `yaml:"diff"`
+}
+
+// Diff is the diff section of veronica config
+type Diff struct {
+	// Mode is "strict"(default) or "semantic", semantic mode also ignores
+	// parentheses, import aliases of the same path and literal spellings like 0x10 and 16.
+	Mode string `yaml:"mode"`
 }
 
 type Service struct {
@@ -22,6 +22,8 @@ func TestParseConfig(t *testing.T) {
 			},
 			want: &Config{
 				Version: "0.1.0",
+				Hooks:   []string{"go.mod", "build/**"},
+				Diff:    Diff{Mode: "semantic"},
 				Services: map[string]*Service{
 					"api-gateway": &Service{
 						Name:       "api-gateway",
@@ -59,6 +61,11 @@ func TestParseConfig(t *testing.T) {
 
 var configV01 = `
 version: 0.1.0
+hooks:
+  - go.mod
+  - build/**
+diff:
+  mode: semantic
 services: 
   api-gateway:
     # main package
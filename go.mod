@@ -6,6 +6,7 @@ require (
 	github.com/bmatcuk/doublestar/v4 v4.8.1
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.9.1
+	golang.org/x/mod v0.20.0
 	golang.org/x/tools v0.24.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -13,6 +14,5 @@ require (
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
-	golang.org/x/mod v0.20.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 )
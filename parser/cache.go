@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// dependencyCacheVersion 是依赖图缓存的格式版本，格式或图中包含的节点、边发生不兼容的变化时递增
+const dependencyCacheVersion = 6
+
+// dependencyCache 是 DependencyInfo 序列化后的格式，图中的边以排序后的列表保存
+type dependencyCache struct {
+	Version         int                   `json:"version"`
+	Nodes           map[string]cachedNode `json:"nodes"`
+	RevGraph        map[string][]string   `json:"rev_graph"`
+	CallRevGraph    map[string][]string   `json:"call_rev_graph"`
+	Externals       map[string][]string   `json:"externals"`
+	Implementations map[string][]string   `json:"implementations"`
+}
+
+type cachedNode struct {
+	Pkg     string `json:"pkg"`
+	File    string `json:"file"`
+	Name    string `json:"name"`
+	Line    int    `json:"line"`
+	EndLine int    `json:"end_line"`
+}
+
+// MarshalJSON 将依赖图序列化为 JSON，节点的 token.Pos 和类型信息不会被保存
+func (d *DependencyInfo) MarshalJSON() ([]byte, error) {
+	cache := dependencyCache{
+		Version:         dependencyCacheVersion,
+		Nodes:           make(map[string]cachedNode, len(d.nodes)),
+		RevGraph:        graphToLists(d.revGraph),
+		CallRevGraph:    graphToLists(d.callRevGraph),
+		Externals:       graphToLists(d.externals),
+		Implementations: graphToLists(d.implementations),
+	}
+	for id, n := range d.nodes {
+		cache.Nodes[id] = cachedNode{Pkg: n.Pkg, File: n.File, Name: n.Name, Line: n.Line, EndLine: n.EndLine}
+	}
+	return json.Marshal(cache)
+}
+
+// UnmarshalJSON 从 MarshalJSON 的结果中恢复依赖图，
+// 恢复后的节点没有 token.Pos 和类型信息，但可以正常查询依赖关系
+func (d *DependencyInfo) UnmarshalJSON(data []byte) error {
+	var cache dependencyCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return err
+	}
+	if cache.Version != dependencyCacheVersion {
+		return fmt.Errorf("unsupported dependency cache version %d", cache.Version)
+	}
+	d.nodes = make(map[string]*node, len(cache.Nodes))
+	for id, n := range cache.Nodes {
+		d.nodes[id] = &node{Pkg: n.Pkg, File: n.File, Name: n.Name, Line: n.Line, EndLine: n.EndLine}
+	}
+	d.revGraph = listsToGraph(cache.RevGraph)
+	// 依赖图是反向图的反向，不需要单独保存
+	d.graph = make(Graph)
+	for dep, nodeIDs := range d.revGraph {
+		for id := range nodeIDs {
+			addDependency(d.graph, id, dep)
+		}
+	}
+	d.callRevGraph = listsToGraph(cache.CallRevGraph)
+	d.externals = listsToGraph(cache.Externals)
+	d.implementations = listsToGraph(cache.Implementations)
+	return nil
+}
+
+func graphToLists(graph Graph) map[string][]string {
+	lists := make(map[string][]string, len(graph))
+	for from, tos := range graph {
+		list := make([]string, 0, len(tos))
+		for to := range tos {
+			list = append(list, to)
+		}
+		sort.Strings(list)
+		lists[from] = list
+	}
+	return lists
+}
+
+func listsToGraph(lists map[string][]string) Graph {
+	graph := make(Graph, len(lists))
+	for from, tos := range lists {
+		for _, to := range tos {
+			addDependency(graph, from, to)
+		}
+	}
+	return graph
+}
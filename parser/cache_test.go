@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDependencyInfoJSON(t *testing.T) {
+	pkgs, err := LoadPackages("./material")
+	if err != nil {
+		t.Fatalf("failed to load packages: %v", err)
+	}
+	want, err := BuildDependency(pkgs)
+	if err != nil {
+		t.Fatalf("failed to build dependency: %v", err)
+	}
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("failed to marshal dependency: %v", err)
+	}
+	got := &DependencyInfo{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("failed to unmarshal dependency: %v", err)
+	}
+
+	if !reflect.DeepEqual(got.graph, want.graph) {
+		t.Errorf("graph = %v, want %v", got.graph, want.graph)
+	}
+	if !reflect.DeepEqual(got.revGraph, want.revGraph) {
+		t.Errorf("revGraph = %v, want %v", got.revGraph, want.revGraph)
+	}
+	if !reflect.DeepEqual(got.callRevGraph, want.callRevGraph) {
+		t.Errorf("callRevGraph = %v, want %v", got.callRevGraph, want.callRevGraph)
+	}
+	if !reflect.DeepEqual(got.externals, want.externals) {
+		t.Errorf("externals = %v, want %v", got.externals, want.externals)
+	}
+	if !reflect.DeepEqual(got.implementations, want.implementations) {
+		t.Errorf("implementations = %v, want %v", got.implementations, want.implementations)
+	}
+	for id := range want.nodes {
+		wantDeps, _ := want.GetDependency(id)
+		gotDeps, err := got.GetDependency(id)
+		if err != nil {
+			t.Fatalf("GetDependency(%s) error: %v", id, err)
+		}
+		sort.Strings(wantDeps)
+		sort.Strings(gotDeps)
+		if !reflect.DeepEqual(gotDeps, wantDeps) {
+			t.Errorf("GetDependency(%s) = %v, want %v", id, gotDeps, wantDeps)
+		}
+	}
+}
+
+func TestDependencyInfoJSONVersion(t *testing.T) {
+	if err := json.Unmarshal([]byte(`{"version": 0}`), &DependencyInfo{}); err == nil {
+		t.Errorf("expected error for unsupported cache version")
+	}
+}
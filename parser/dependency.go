@@ -5,18 +5,24 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
 
-// node 表示一个顶级声明节点，使用"文件:标识符"作为唯一标识。
+// node 表示一个顶级声明或结构体字段节点，使用"包名:标识符"作为唯一标识，
+// 结构体字段的标识符为"类型名.字段名"。
 type node struct {
-	Pos  token.Pos
-	File string // 文件名（仅基础名）
-	Name string // 标识符名称
-	Obj  types.Object
+	Pos     token.Pos
+	Pkg     string // 完整包名
+	File    string // 文件名（仅基础名）
+	Name    string // 标识符名称
+	Line    int    // 声明的起始行
+	EndLine int    // 声明的结束行
+	Obj     types.Object
 }
 
 // interfaceInfo store interface related information
@@ -36,7 +42,9 @@ type methodInfo struct {
 // Graph 存储节点之间的依赖关系，边表示"当前节点依赖于另一个节点"
 type Graph map[string]map[string]struct{}
 
-// GetObjectID 获取完整标识符路径，格式：包名/文件名:标识符
+// GetObjectID 获取完整标识符路径，格式：包名:标识符，声明所在的文件不影响它的标识，
+// 在文件之间移动声明时标识保持不变。
+// init 函数和空标识符在同一个包中可以声明多次，它们的格式为：包名/文件名:标识符
 // pkg应为包括go module name的完整包名，例如：github.com/bootun/veronica/parser
 func GetObjectID(pkg string, fileName string, obj string) string {
 	if pkg == "" {
@@ -48,7 +56,10 @@ func GetObjectID(pkg string, fileName string, obj string) string {
 	if obj == "" {
 		panic("obj is empty")
 	}
-	return fmt.Sprintf("%s/%s:%s", pkg, fileName, obj)
+	if obj == "init" || obj == "_" {
+		return fmt.Sprintf("%s/%s:%s", pkg, fileName, obj)
+	}
+	return fmt.Sprintf("%s:%s", pkg, obj)
 }
 
 func GetNodeId(pkg *packages.Package, node ast.Node) string {
@@ -70,35 +81,141 @@ func GetNodeId(pkg *packages.Package, node ast.Node) string {
 }
 
 type DependencyInfo struct {
-	// 项目内所有的顶级声明, key: NodeID, value: Node
+	// 项目内所有的顶级声明和结构体字段, key: NodeID, value: Node
 	nodes map[string]*node
+	// 依赖图, key: NodeID, value: key依赖的NodeID列表
+	graph Graph
 	// 依赖图的反向图, key: NodeID, value: 依赖key的NodeID列表
 	revGraph Graph
+	// 调用图的反向图, key: NodeID, value: 调用或引用了key的值的NodeID列表，是 revGraph 的子图
+	callRevGraph Graph
+	// 对项目外部包的引用, key: 外部包路径, value: 引用了该包中对象的NodeID列表
+	externals Graph
+	// 接口的实现, key: 接口的NodeID, value: 实现了该接口的类型的NodeID列表
+	implementations Graph
 }
 
+// Direction 是遍历依赖图的方向
+type Direction string
+
+const (
+	// DirectionForward 查找目标直接或间接依赖的节点
+	DirectionForward Direction = "forward"
+	// DirectionReverse 查找直接或间接依赖目标的节点
+	DirectionReverse Direction = "reverse"
+)
+
 // GetDependency 获取 targetID 的依赖节点
 func (d *DependencyInfo) GetDependency(targetID string) ([]string, error) {
+	return d.reachable(d.revGraph, targetID, 0)
+}
+
+// GetCallDependency 获取通过调用或引用值直接或间接依赖 targetID 的节点，
+// 只引用了 targetID 所属类型的节点不会被返回，适用于只有函数体发生变化的情况
+func (d *DependencyInfo) GetCallDependency(targetID string) ([]string, error) {
+	return d.reachable(d.callRevGraph, targetID, 0)
+}
+
+// Traverse 沿 direction 方向遍历依赖图，返回从 targetID 出发在 depth 步之内可以到达的节点，
+// depth 小于等于 0 时不限制步数
+func (d *DependencyInfo) Traverse(targetID string, direction Direction, depth int) ([]string, error) {
+	switch direction {
+	case DirectionForward:
+		return d.reachable(d.graph, targetID, depth)
+	case DirectionReverse:
+		return d.reachable(d.revGraph, targetID, depth)
+	default:
+		return nil, fmt.Errorf("invalid direction: %s", direction)
+	}
+}
+
+// reachable 获取在图 graph 中从 targetID 出发 depth 步之内可以到达的节点，depth 小于等于 0 时不限制步数
+func (d *DependencyInfo) reachable(graph Graph, targetID string, depth int) ([]string, error) {
 	if _, ok := d.nodes[targetID]; !ok {
 		return nil, fmt.Errorf("target %s is not defined in project", targetID)
 	}
 
+	// 广度优先搜索，保证每个节点都以最少的步数被访问到，
+	// 存在循环依赖时 targetID 本身也可能被返回
 	visited := make(map[string]struct{})
-	var dfs func(string)
-	dfs = func(node string) {
-		for dep := range d.revGraph[node] {
-			if _, ok := visited[dep]; !ok {
-				visited[dep] = struct{}{}
-				dfs(dep)
+	deps := make([]string, 0)
+	queue := []string{targetID}
+	for step := 1; len(queue) > 0 && (depth <= 0 || step <= depth); step++ {
+		var next []string
+		for _, cur := range queue {
+			for dep := range graph[cur] {
+				if _, ok := visited[dep]; !ok {
+					visited[dep] = struct{}{}
+					deps = append(deps, dep)
+					next = append(next, dep)
+				}
+			}
+		}
+		queue = next
+	}
+	return deps, nil
+}
+
+// GetPath 获取一条从 fromID 到 targetID 的最短依赖路径(targetID 直接或间接依赖 fromID)，
+// 路径包括首尾两个节点，不存在依赖关系时返回 nil
+func (d *DependencyInfo) GetPath(fromID, targetID string) []string {
+	return d.shortestPath(d.revGraph, fromID, targetID)
+}
+
+// GetCallPath 与 GetPath 相同，但只经过调用或引用值的依赖关系
+func (d *DependencyInfo) GetCallPath(fromID, targetID string) []string {
+	return d.shortestPath(d.callRevGraph, fromID, targetID)
+}
+
+func (d *DependencyInfo) shortestPath(revGraph Graph, fromID, targetID string) []string {
+	if _, ok := d.nodes[fromID]; !ok {
+		return nil
+	}
+	// 在反向图上广度优先搜索，prev 记录每个节点在最短路径上的前一个节点
+	prev := map[string]string{fromID: ""}
+	queue := []string{fromID}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == targetID {
+			var path []string
+			for id := cur; id != ""; id = prev[id] {
+				path = append([]string{id}, path...)
+			}
+			return path
+		}
+		// 按节点ID排序，保证多条最短路径时结果稳定
+		deps := make([]string, 0, len(revGraph[cur]))
+		for dep := range revGraph[cur] {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if _, ok := prev[dep]; !ok {
+				prev[dep] = cur
+				queue = append(queue, dep)
 			}
 		}
 	}
-	dfs(targetID)
+	return nil
+}
 
-	deps := make([]string, 0, len(visited))
-	for id := range visited {
-		deps = append(deps, id)
+// GetExternalReferrers 获取引用了项目外部包中对象的节点，match 接收外部包的路径，返回该包是否需要关注
+func (d *DependencyInfo) GetExternalReferrers(match func(pkgPath string) bool) []string {
+	referrers := make(map[string]struct{})
+	for pkgPath, nodeIDs := range d.externals {
+		if !match(pkgPath) {
+			continue
+		}
+		for id := range nodeIDs {
+			referrers[id] = struct{}{}
+		}
 	}
-	return deps, nil
+	ids := make([]string, 0, len(referrers))
+	for id := range referrers {
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 // BuildDependency 构建依赖关系图
@@ -115,6 +232,32 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 
 	// 依赖图: key->NodeID, value->依赖Key的NodeID列表
 	graph := make(Graph)
+	// 调用图，只包含函数调用和对变量、常量的引用，不包含对类型的引用，
+	// 以及根据方法名推测的接口调用
+	callGraph := make(Graph)
+
+	// 项目内所有包的路径，用于区分项目外部的对象
+	projectPkgs := make(map[string]struct{})
+	for _, pkg := range pkgs {
+		projectPkgs[pkg.PkgPath] = struct{}{}
+	}
+	// 对项目外部包的引用: key->外部包路径, value->引用了该包中对象的NodeID列表
+	externals := make(Graph)
+	// addExternal 如果 obj 是项目外部(第三方或标准库)的对象，记录 curNodeID 对其所在包的引用
+	addExternal := func(curNodeID string, obj types.Object) {
+		// 内置对象(如 len, error)没有所属的包，包名本身交给选择器右侧的标识符处理
+		if obj.Pkg() == nil {
+			return
+		}
+		if _, ok := obj.(*types.PkgName); ok {
+			return
+		}
+		pkgPath := obj.Pkg().Path()
+		if _, ok := projectPkgs[pkgPath]; ok {
+			return
+		}
+		addDependency(externals, pkgPath, curNodeID)
+	}
 
 	// 遍历所有包和文件，提取顶级声明，构建接口表
 	for _, pkg := range pkgs {
@@ -138,10 +281,13 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 					}
 					nodesMap[obj] = id
 					nodesInfo[id] = &node{
-						Pos:  d.Pos(),
-						File: baseFilename,
-						Name: funcName,
-						Obj:  obj,
+						Pos:     d.Pos(),
+						Pkg:     pkg.ID,
+						File:    baseFilename,
+						Name:    funcName,
+						Line:    fset.Position(d.Pos()).Line,
+						EndLine: fset.Position(d.End()).Line,
+						Obj:     obj,
 					}
 
 				// constant, type or variable declaration
@@ -161,10 +307,13 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 								}
 								nodesMap[obj] = id
 								nodesInfo[id] = &node{
-									Pos:  ident.Pos(),
-									File: baseFilename,
-									Name: ident.Name,
-									Obj:  obj,
+									Pos:     ident.Pos(),
+									Pkg:     pkg.ID,
+									File:    baseFilename,
+									Name:    ident.Name,
+									Line:    fset.Position(s.Pos()).Line,
+									EndLine: fset.Position(s.End()).Line,
+									Obj:     obj,
 								}
 							}
 
@@ -180,10 +329,43 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 							}
 							nodesMap[obj] = id
 							nodesInfo[id] = &node{
-								Pos:  s.Pos(),
-								File: baseFilename,
-								Name: s.Name.Name,
-								Obj:  obj,
+								Pos:     s.Pos(),
+								Pkg:     pkg.ID,
+								File:    baseFilename,
+								Name:    s.Name.Name,
+								Line:    fset.Position(s.Pos()).Line,
+								EndLine: fset.Position(s.End()).Line,
+								Obj:     obj,
+							}
+
+							// 结构体的字段作为类型的子节点，字段依赖于它所属的类型，
+							// 只读写某个字段的声明只依赖该字段，不会受到其他字段变化的影响
+							if st, ok := s.Type.(*ast.StructType); ok {
+								for _, field := range st.Fields.List {
+									for _, ident := range FieldIdents(field) {
+										// 空白字段只用于填充，可以出现多次，无法被读写
+										if ident.Name == "_" {
+											continue
+										}
+										fieldObj := pkg.TypesInfo.Defs[ident]
+										if fieldObj == nil {
+											continue
+										}
+										fieldName := s.Name.Name + "." + ident.Name
+										fieldID := GetObjectID(pkg.ID, baseFilename, fieldName)
+										nodesMap[fieldObj] = fieldID
+										nodesInfo[fieldID] = &node{
+											Pos:     ident.Pos(),
+											Pkg:     pkg.ID,
+											File:    baseFilename,
+											Name:    fieldName,
+											Line:    fset.Position(field.Pos()).Line,
+											EndLine: fset.Position(field.End()).Line,
+											Obj:     fieldObj,
+										}
+										addDependency(graph, fieldID, id)
+									}
+								}
 							}
 
 							// handle type parameters(generic type)
@@ -239,6 +421,7 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 									if ident, ok := n.(*ast.Ident); ok {
 										// 检查是否引用了其他类型
 										if refObj := pkg.TypesInfo.Uses[ident]; refObj != nil {
+											addExternal(id, refObj)
 											if depID, exists := nodesMap[refObj]; exists && depID != id {
 												addDependency(graph, id, depID)
 											}
@@ -272,10 +455,8 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 			// 获取方法名
 			methodName := parts[1]
 
-			// 构造类型的完整ID
-			typeFile := node.File
-			pkgName := strings.TrimSuffix(nodeID, "/"+typeFile+":"+node.Name)
-			typeID := GetObjectID(pkgName, typeFile, recvType) // 当前receiver的唯一标识
+			// 构造类型的完整ID，方法与接收器类型只需要在同一个包中，可以位于不同的文件
+			typeID := GetObjectID(node.Pkg, node.File, recvType) // 当前receiver的唯一标识
 
 			if _, ok := typeMethodsMap[typeID]; !ok {
 				typeMethodsMap[typeID] = make(map[string]string)
@@ -321,6 +502,21 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 						return true
 					})
 				}
+				// 不带字段名的结构体字面量按顺序为每一个字段赋值
+				if len(compLit.Elts) > 0 {
+					if _, keyed := compLit.Elts[0].(*ast.KeyValueExpr); !keyed {
+						if t := pkg.TypesInfo.TypeOf(compLit); t != nil {
+							if st, ok := t.Underlying().(*types.Struct); ok {
+								for i := 0; i < st.NumFields(); i++ {
+									if depID, ok := nodesMap[st.Field(i).Origin()]; ok && depID != curNodeID {
+										addDependency(graph, curNodeID, depID)
+										addDependency(callGraph, curNodeID, depID)
+									}
+								}
+							}
+						}
+					}
+				}
 				// 处理结构体字段
 				for _, elt := range compLit.Elts {
 					if kv, ok := elt.(*ast.KeyValueExpr); ok {
@@ -380,6 +576,16 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 
 			// 处理选择器表达式（如 a.b 形式的调用）
 			if sel, ok := n.(*ast.SelectorExpr); ok {
+				// 通过嵌入字段访问提升的字段或方法时(如 a.Base.b 简写为 a.b)，
+				// 同样读取了路径上的每一个嵌入字段，最后一个字段由右侧的标识符处理
+				if selection, ok := pkg.TypesInfo.Selections[sel]; ok {
+					for _, fieldID := range embeddedFieldIDs(nodesMap, selection) {
+						if fieldID != curNodeID {
+							addDependency(graph, curNodeID, fieldID)
+							addDependency(callGraph, curNodeID, fieldID)
+						}
+					}
+				}
 				var obj types.Object
 				var objType types.Type
 
@@ -555,6 +761,23 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 					}
 				}
 
+				// 调用图中只记录通过该接口实际可能调用到的方法
+				if iface, ok := interfaceOf(objType); ok {
+					for typeID, typeMethods := range typeMethodsMap {
+						methodID, found := typeMethods[methodName]
+						if !found {
+							continue
+						}
+						typeNode, ok := nodesInfo[typeID]
+						if !ok || typeNode.Obj == nil {
+							continue
+						}
+						if t := typeNode.Obj.Type(); types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface) {
+							addDependency(callGraph, curNodeID, methodID)
+						}
+					}
+				}
+
 				if isIface {
 					// 它是接口类型，需要查找所有实现了这个接口的类型
 					// 首先尝试查找精确匹配的接口（如果已经记录在 interfacesInfo 中）
@@ -613,17 +836,27 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 			if obj == nil {
 				return true
 			}
+			addExternal(curNodeID, obj)
 			// 如果obj是函数，则获取其原始函数
 			if f, ok := obj.(*types.Func); ok {
 				if f.Origin() != nil {
 					obj = f.Origin()
 				}
 			}
+			// 泛型类型实例化后的字段同样需要获取其原始字段
+			if v, ok := obj.(*types.Var); ok && v.IsField() {
+				obj = v.Origin()
+			}
 			// 判断这个对象是否在我们的顶级声明中（只考虑同一项目内部）
 			if depID, ok := nodesMap[obj]; ok {
 				// 避免自引用
 				if depID != curNodeID {
 					addDependency(graph, curNodeID, depID)
+					// 调用函数、读写变量或常量时，它们的值会影响当前节点的行为
+					switch obj.(type) {
+					case *types.Func, *types.Var, *types.Const:
+						addDependency(callGraph, curNodeID, depID)
+					}
 				}
 			}
 			return true
@@ -650,6 +883,7 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 							ast.Inspect(field.Type, func(n ast.Node) bool {
 								if ident, ok := n.(*ast.Ident); ok {
 									if obj := pkg.TypesInfo.Uses[ident]; obj != nil {
+										addExternal(curID, obj)
 										if depID, ok := nodesMap[obj]; ok && depID != curID {
 											addDependency(graph, curID, depID)
 										}
@@ -666,6 +900,7 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 							ast.Inspect(field.Type, func(n ast.Node) bool {
 								if ident, ok := n.(*ast.Ident); ok {
 									if obj := pkg.TypesInfo.Uses[ident]; obj != nil {
+										addExternal(curID, obj)
 										if depID, ok := nodesMap[obj]; ok && depID != curID {
 											addDependency(graph, curID, depID)
 										}
@@ -682,6 +917,7 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 							ast.Inspect(field.Type, func(n ast.Node) bool {
 								if ident, ok := n.(*ast.Ident); ok {
 									if obj := pkg.TypesInfo.Uses[ident]; obj != nil {
+										addExternal(curID, obj)
 										if depID, ok := nodesMap[obj]; ok && depID != curID {
 											addDependency(graph, curID, depID)
 										}
@@ -694,16 +930,57 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 					// 处理函数体
 					collectDependencies(d.Body, curID, pkg)
 				case *ast.GenDecl:
+					// const 分组中省略了类型和表达式的常量，等价于重复前面最近一个带表达式的常量的类型和表达式
+					var constType ast.Expr
+					var constValues []ast.Expr
 					for _, spec := range d.Specs {
 						switch s := spec.(type) {
 						case *ast.ValueSpec:
+							typ, values := s.Type, s.Values
+							if d.Tok == token.CONST {
+								if len(values) > 0 {
+									constType, constValues = typ, values
+								} else {
+									typ, values = constType, constValues
+								}
+							}
 							for _, ident := range s.Names {
 								curID := GetObjectID(pkg.ID, baseFilename, ident.Name)
+								// 声明的类型，如 var repo TagRepo 没有初始化表达式，只通过类型产生依赖
+								if typ != nil {
+									collectDependencies(typ, curID, pkg)
+								}
 								// 如果有初始化表达式，则扫描之
-								for _, expr := range s.Values {
+								for _, expr := range values {
 									collectDependencies(expr, curID, pkg)
 								}
 							}
+						case *ast.TypeSpec:
+							st, ok := s.Type.(*ast.StructType)
+							if !ok {
+								continue
+							}
+							// 字段依赖于它的类型中引用的其他类型，
+							// 所有类型的节点在第一次遍历后才全部就绪，因此在这里处理
+							for _, field := range st.Fields.List {
+								for _, ident := range FieldIdents(field) {
+									if ident.Name == "_" {
+										continue
+									}
+									fieldID := GetObjectID(pkg.ID, baseFilename, s.Name.Name+"."+ident.Name)
+									ast.Inspect(field.Type, func(n ast.Node) bool {
+										if ident, ok := n.(*ast.Ident); ok {
+											if obj := pkg.TypesInfo.Uses[ident]; obj != nil {
+												addExternal(fieldID, obj)
+												if depID, ok := nodesMap[obj]; ok {
+													addDependency(graph, fieldID, depID)
+												}
+											}
+										}
+										return true
+									})
+								}
+							}
 						}
 					}
 				}
@@ -721,10 +998,27 @@ func BuildDependency(pkgs []*packages.Package) (*DependencyInfo, error) {
 			revGraph[dep][nodeID] = struct{}{}
 		}
 	}
+	callRevGraph := make(Graph)
+	for nodeID, deps := range callGraph {
+		for dep := range deps {
+			addDependency(callRevGraph, dep, nodeID)
+		}
+	}
+
+	implementations := make(Graph)
+	for ifaceID, iface := range interfaceMap {
+		for _, implID := range iface.Implements {
+			addDependency(implementations, ifaceID, implID)
+		}
+	}
 
 	return &DependencyInfo{
-		nodes:    nodesInfo,
-		revGraph: revGraph,
+		nodes:           nodesInfo,
+		graph:           graph,
+		revGraph:        revGraph,
+		callRevGraph:    callRevGraph,
+		externals:       externals,
+		implementations: implementations,
 	}, nil
 }
 
@@ -861,6 +1155,16 @@ func parseInterfaceImplementations(nodesInfo map[string]*node, interfacesInfo ma
 	return nil
 }
 
+// interfaceOf 返回 t 或 t 指向的类型的底层接口类型，
+// 类型参数的底层类型是它的约束
+func interfaceOf(t types.Type) (*types.Interface, bool) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	iface, ok := t.Underlying().(*types.Interface)
+	return iface, ok
+}
+
 // exprToString 返回表达式的字符串表示（对标识符和星号类型作简单处理）
 func exprToString(expr ast.Expr) string {
 	switch e := expr.(type) {
@@ -889,6 +1193,54 @@ func GetFuncOrMethodName(fn *ast.FuncDecl) string {
 	return fmt.Sprintf("(%s).%s", recv, fn.Name.Name)
 }
 
+// embeddedFieldIDs 返回选择器 selection 经过的嵌入字段对应的节点，
+// 不包括选择器最终选中的字段或方法
+func embeddedFieldIDs(nodesMap map[types.Object]string, selection *types.Selection) []string {
+	var ids []string
+	t := selection.Recv()
+	index := selection.Index()
+	for _, i := range index[:len(index)-1] {
+		if ptr, ok := t.Underlying().(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			break
+		}
+		field := st.Field(i)
+		if id, ok := nodesMap[field.Origin()]; ok {
+			ids = append(ids, id)
+		}
+		t = field.Type()
+	}
+	return ids
+}
+
+// FieldIdents 返回结构体字段声明的字段名，嵌入字段的字段名是它的类型名，
+// 例如 *pkg.Base 和 Base[T] 的字段名都是 Base
+func FieldIdents(field *ast.Field) []*ast.Ident {
+	if len(field.Names) > 0 {
+		return field.Names
+	}
+	typ := field.Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		case *ast.SelectorExpr:
+			return []*ast.Ident{t.Sel}
+		case *ast.Ident:
+			return []*ast.Ident{t}
+		default:
+			return nil
+		}
+	}
+}
+
 // signaturesCompatible 检查类型方法的签名是否与接口方法的签名兼容
 func signaturesCompatible(ifaceMethodSig, typeMethodSig *types.Signature) bool {
 	// 检查接口方法是否有类型参数
@@ -1209,18 +1561,129 @@ func signaturesCompatible(ifaceMethodSig, typeMethodSig *types.Signature) bool {
 	return ifaceMethodSig.Variadic() == typeMethodSig.Variadic()
 }
 
-func LoadPackages(repo string) ([]*packages.Package, error) {
+// loadMode 是加载项目中的包时需要的信息
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+
+// LoadPackages 加载项目中的包，patterns 为空时加载项目中所有的包
+func LoadPackages(repo string, patterns ...string) ([]*packages.Package, error) {
+	return loadPackages(repo, loadMode, patterns)
+}
+
+// LoadSyntax 只解析项目中的包的语法树，不进行类型检查，
+// 适用于依赖图已经从缓存中读取、只需要对比 AST 的情况
+func LoadSyntax(repo string, patterns ...string) ([]*packages.Package, error) {
+	return loadPackages(repo, packages.NeedName|packages.NeedFiles|packages.NeedSyntax, patterns)
+}
+
+func loadPackages(repo string, mode packages.LoadMode, patterns []string) ([]*packages.Package, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
 	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Mode: mode,
 		Dir:  repo,
 	}
-	pkgs, err := packages.Load(cfg, "./...")
+	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
 		return nil, fmt.Errorf("parser project AST failed in %s: %v", repo, err)
 	}
 	return pkgs, nil
 }
 
+// AffectedPackages 返回 files 所在的包，以及直接或间接导入了这些包的项目内的包，
+// files 为相对于 repo 的路径，非 Go 文件会被忽略。
+// 只加载这些包时，仅通过接口调用到变更代码的包可能不会被加载
+func AffectedPackages(repo string, files []string) ([]string, error) {
+	// 只加载包的导入关系，不进行类型检查
+	metas, err := loadPackages(repo, packages.NeedName|packages.NeedImports|packages.NeedModule, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 变更的文件所在目录对应的包路径，文件被删除时，同一目录下的包同样发生了变化。
+	// 使用包路径而不是目录进行匹配，这样 repo 是相对路径或经过了符号链接时也可以正确匹配，
+	// 目录中只有测试文件或者所有文件都被构建约束排除的包也不会被遗漏
+	var module *packages.Module
+	for _, pkg := range metas {
+		if pkg.Module != nil && pkg.Module.Main {
+			module = pkg.Module
+			break
+		}
+	}
+	if module == nil {
+		return nil, fmt.Errorf("no main module found in %s", repo)
+	}
+	repoPath, err := importPathOf(repo, module)
+	if err != nil {
+		return nil, err
+	}
+	changedPkgs := make(map[string]struct{})
+	for _, file := range files {
+		if filepath.Ext(file) != ".go" {
+			continue
+		}
+		changedPkgs[path.Join(repoPath, filepath.ToSlash(filepath.Dir(file)))] = struct{}{}
+	}
+	// 导入关系的反向图, key: 包ID, value: 导入了该包的包ID列表
+	importers := make(Graph)
+	var queue []string
+	for _, pkg := range metas {
+		for _, imp := range pkg.Imports {
+			addDependency(importers, imp.ID, pkg.ID)
+		}
+		if _, ok := changedPkgs[pkg.PkgPath]; ok {
+			queue = append(queue, pkg.ID)
+		}
+	}
+
+	// 从变更的包出发，找到所有直接或间接导入了它们的包
+	affected := make(map[string]struct{})
+	for _, id := range queue {
+		affected[id] = struct{}{}
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for importer := range importers[cur] {
+			if _, ok := affected[importer]; !ok {
+				affected[importer] = struct{}{}
+				queue = append(queue, importer)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(affected))
+	for id := range affected {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// importPathOf 返回目录 dir 在 module 中对应的导入路径
+func importPathOf(dir string, module *packages.Module) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	// 比较之前解析符号链接，go 命令报告的模块目录可能与 dir 的写法不同
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return "", err
+	}
+	moduleDir, err := filepath.EvalSymlinks(module.Dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(moduleDir, dir)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in module %s", dir, module.Path)
+	}
+	return path.Join(module.Path, filepath.ToSlash(rel)), nil
+}
+
 // addDependency adds a dependency edge from fromID to toID in the graph, avoiding self-references.
 func addDependency(graph Graph, fromID, toID string) {
 	if fromID == toID {
@@ -4,6 +4,8 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -75,3 +77,253 @@ func TestGetFuncOrMethodName(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPath(t *testing.T) {
+	// Entity <- GetOneTagById <- GetPlayletInfo
+	// Entity <- Helper <- Repo <- GetPlayletInfo
+	depInfo := &DependencyInfo{
+		nodes: map[string]*node{
+			"Entity":         {},
+			"GetOneTagById":  {},
+			"Helper":         {},
+			"Repo":           {},
+			"GetPlayletInfo": {},
+			"Unrelated":      {},
+		},
+		revGraph: Graph{
+			"Entity":        {"GetOneTagById": {}, "Helper": {}},
+			"GetOneTagById": {"GetPlayletInfo": {}},
+			"Helper":        {"Repo": {}},
+			"Repo":          {"GetPlayletInfo": {}},
+		},
+	}
+	tests := []struct {
+		name   string
+		from   string
+		target string
+		want   []string
+	}{
+		{name: "shortest", from: "Entity", target: "GetPlayletInfo", want: []string{"Entity", "GetOneTagById", "GetPlayletInfo"}},
+		{name: "self", from: "Repo", target: "Repo", want: []string{"Repo"}},
+		{name: "unreachable", from: "Unrelated", target: "GetPlayletInfo", want: nil},
+		{name: "reverse-direction", from: "GetPlayletInfo", target: "Entity", want: nil},
+		{name: "undefined", from: "Undefined", target: "GetPlayletInfo", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := depInfo.GetPath(tt.from, tt.target); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetObjectID(t *testing.T) {
+	tests := []struct {
+		name     string
+		pkg      string
+		fileName string
+		obj      string
+		want     string
+	}{
+		{name: "func", pkg: "example.com/pkg/store", fileName: "store.go", obj: "GetTag", want: "example.com/pkg/store:GetTag"},
+		{name: "method", pkg: "example.com/pkg/store", fileName: "store.go", obj: "(*Store).Get", want: "example.com/pkg/store:(*Store).Get"},
+		{name: "init", pkg: "example.com/pkg/store", fileName: "store.go", obj: "init", want: "example.com/pkg/store/store.go:init"},
+		{name: "blank", pkg: "example.com/pkg/store", fileName: "store.go", obj: "_", want: "example.com/pkg/store/store.go:_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetObjectID(tt.pkg, tt.fileName, tt.obj); got != tt.want {
+				t.Errorf("GetObjectID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCallDependency(t *testing.T) {
+	pkgs, err := LoadPackages("./material")
+	if err != nil {
+		t.Fatalf("failed to load packages: %v", err)
+	}
+	depInfo, err := BuildDependency(pkgs)
+	if err != nil {
+		t.Fatalf("failed to build dependency: %v", err)
+	}
+	const pkg = "github.com/bootun/veronica/parser/material/shop"
+	target := pkg + ":(*Cart).Total"
+
+	deps, err := depInfo.GetCallDependency(target)
+	if err != nil {
+		t.Fatalf("GetCallDependency() error: %v", err)
+	}
+	sort.Strings(deps)
+	want := []string{pkg + ":Charge", pkg + ":Checkout", pkg + ":Price"}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("GetCallDependency() = %v, want %v", deps, want)
+	}
+
+	// 完整的依赖图中会根据方法名推测接口调用，调用同名方法的 Bill 也会被认为依赖 (*Cart).Total
+	deps, err = depInfo.GetDependency(target)
+	if err != nil {
+		t.Fatalf("GetDependency() error: %v", err)
+	}
+	for _, id := range append(want, pkg+":Bill") {
+		if !contains(deps, id) {
+			t.Errorf("GetDependency() = %v, want to contain %s", deps, id)
+		}
+	}
+}
+
+func TestFieldDependency(t *testing.T) {
+	pkgs, err := LoadPackages("./material")
+	if err != nil {
+		t.Fatalf("failed to load packages: %v", err)
+	}
+	depInfo, err := BuildDependency(pkgs)
+	if err != nil {
+		t.Fatalf("failed to build dependency: %v", err)
+	}
+	const pkg = "github.com/bootun/veronica/parser/material/shop"
+	tests := []struct {
+		name   string
+		target string
+		want   []string
+	}{
+		{name: "read", target: pkg + ":Config.Name", want: []string{pkg + ":UseName"}},
+		{name: "composite-literal", target: pkg + ":Config.Timeout", want: []string{pkg + ":NewConfig"}},
+		{name: "unused", target: pkg + ":Config.Extra", want: []string{}},
+		{name: "promoted", target: pkg + ":Base.ID", want: []string{pkg + ":DefaultBase", pkg + ":UseID"}},
+		{name: "embedded", target: pkg + ":Config.Base", want: []string{pkg + ":UseID"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, err := depInfo.GetDependency(tt.target)
+			if err != nil {
+				t.Fatalf("GetDependency() error: %v", err)
+			}
+			sort.Strings(deps)
+			if !reflect.DeepEqual(deps, tt.want) {
+				t.Errorf("GetDependency(%s) = %v, want %v", tt.target, deps, tt.want)
+			}
+		})
+	}
+
+	// 字段依赖于它所属的类型，类型的变化会传播到读写字段的声明
+	deps, err := depInfo.GetDependency(pkg + ":Config")
+	if err != nil {
+		t.Fatalf("GetDependency() error: %v", err)
+	}
+	for _, id := range []string{pkg + ":Config.Name", pkg + ":UseName", pkg + ":UseID"} {
+		if !contains(deps, id) {
+			t.Errorf("GetDependency() = %v, want to contain %s", deps, id)
+		}
+	}
+}
+
+func TestTraverse(t *testing.T) {
+	pkgs, err := LoadPackages("./material")
+	if err != nil {
+		t.Fatalf("failed to load packages: %v", err)
+	}
+	depInfo, err := BuildDependency(pkgs)
+	if err != nil {
+		t.Fatalf("failed to build dependency: %v", err)
+	}
+	const pkg = "github.com/bootun/veronica/parser/material/shop"
+	tests := []struct {
+		name      string
+		target    string
+		direction Direction
+		depth     int
+		want      []string
+	}{
+		{name: "forward-depth-1", target: pkg + ":UseName", direction: DirectionForward, depth: 1, want: []string{pkg + ":Config", pkg + ":Config.Name"}},
+		{name: "forward-unlimited", target: pkg + ":UseName", direction: DirectionForward, want: []string{pkg + ":Base", pkg + ":Config", pkg + ":Config.Name"}},
+		{name: "reverse-depth-1", target: pkg + ":Base", direction: DirectionReverse, depth: 1, want: []string{pkg + ":Base.ID", pkg + ":Config", pkg + ":Config.Base", pkg + ":DefaultBase"}},
+		{name: "reverse-depth-2", target: pkg + ":Base.ID", direction: DirectionReverse, depth: 2, want: []string{pkg + ":DefaultBase", pkg + ":UseID"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, err := depInfo.Traverse(tt.target, tt.direction, tt.depth)
+			if err != nil {
+				t.Fatalf("Traverse() error: %v", err)
+			}
+			sort.Strings(deps)
+			if !reflect.DeepEqual(deps, tt.want) {
+				t.Errorf("Traverse(%s, %s, %d) = %v, want %v", tt.target, tt.direction, tt.depth, deps, tt.want)
+			}
+		})
+	}
+
+	if _, err := depInfo.Traverse(pkg+":UseName", "sideways", 0); err == nil {
+		t.Errorf("expected error for invalid direction")
+	}
+}
+
+func TestValueSpecTypeDependency(t *testing.T) {
+	pkgs, err := LoadPackages("./material")
+	if err != nil {
+		t.Fatalf("failed to load packages: %v", err)
+	}
+	depInfo, err := BuildDependency(pkgs)
+	if err != nil {
+		t.Fatalf("failed to build dependency: %v", err)
+	}
+	const pkg = "github.com/bootun/veronica/parser/material/shop"
+	tests := []struct {
+		name   string
+		target string
+		want   []string
+	}{
+		{name: "var-without-initializer", target: pkg + ":Pricer", want: []string{pkg + ":DefaultPricer", pkg + ":Price"}},
+		{name: "typed-const", target: pkg + ":Amount", want: []string{pkg + ":Limit"}},
+		{name: "implicitly-typed-const", target: pkg + ":Kind", want: []string{pkg + ":KindA", pkg + ":KindB", pkg + ":KindName"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, err := depInfo.GetDependency(tt.target)
+			if err != nil {
+				t.Fatalf("GetDependency() error: %v", err)
+			}
+			for _, id := range tt.want {
+				if !contains(deps, id) {
+					t.Errorf("GetDependency(%s) = %v, want to contain %s", tt.target, deps, id)
+				}
+			}
+		})
+	}
+}
+
+func TestAffectedPackages(t *testing.T) {
+	const material = "github.com/bootun/veronica/parser/material"
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{name: "importer", files: []string{"bufutil/bufutil.go"}, want: []string{material + "/bufutil", material + "/shop"}},
+		{name: "leaf", files: []string{"shop/shop.go"}, want: []string{material + "/shop"}},
+		{name: "deleted-file", files: []string{"shop/removed.go"}, want: []string{material + "/shop"}},
+		{name: "non-go", files: []string{"bufutil/README.md"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AffectedPackages("./material", tt.files)
+			if err != nil {
+				t.Fatalf("AffectedPackages() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AffectedPackages(%v) = %v, want %v", tt.files, got, tt.want)
+			}
+		})
+	}
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Subgraph 返回从 roots 出发沿 direction 方向 depth 步之内可以到达的节点(包括 roots 本身)及它们之间的依赖关系，
+// roots 为空时返回整个依赖图。返回的图中每个节点都有一项，边总是表示"当前节点依赖于另一个节点"
+func (d *DependencyInfo) Subgraph(roots []string, direction Direction, depth int) (Graph, error) {
+	included := make(map[string]struct{})
+	if len(roots) == 0 {
+		for id := range d.nodes {
+			included[id] = struct{}{}
+		}
+	}
+	for _, root := range roots {
+		deps, err := d.Traverse(root, direction, depth)
+		if err != nil {
+			return nil, err
+		}
+		included[root] = struct{}{}
+		for _, id := range deps {
+			included[id] = struct{}{}
+		}
+	}
+
+	sub := make(Graph, len(included))
+	for id := range included {
+		sub[id] = make(map[string]struct{})
+		for dep := range d.graph[id] {
+			if _, ok := included[dep]; ok {
+				sub[id][dep] = struct{}{}
+			}
+		}
+	}
+	return sub, nil
+}
+
+// CollapsePackages 将声明之间的依赖图 g 合并为包之间的依赖图，
+// 同一个包中的声明之间的依赖关系会被忽略
+func (d *DependencyInfo) CollapsePackages(g Graph) Graph {
+	pkgs := make(Graph)
+	for id, deps := range g {
+		n, ok := d.nodes[id]
+		if !ok {
+			continue
+		}
+		if _, ok := pkgs[n.Pkg]; !ok {
+			pkgs[n.Pkg] = make(map[string]struct{})
+		}
+		for dep := range deps {
+			if depNode, ok := d.nodes[dep]; ok && depNode.Pkg != n.Pkg {
+				pkgs[n.Pkg][depNode.Pkg] = struct{}{}
+			}
+		}
+	}
+	return pkgs
+}
+
+// WeightedGraph 是带权重的依赖图, key: 节点, value: key依赖的节点 -> 权重
+type WeightedGraph map[string]map[string]int
+
+// PackageGraph 返回包之间的依赖图，边的权重是两个包的声明之间依赖关系的数量，
+// 项目中的每个包都有一项，同一个包中的声明之间的依赖关系会被忽略
+func (d *DependencyInfo) PackageGraph() WeightedGraph {
+	pkgs := make(WeightedGraph)
+	for _, n := range d.nodes {
+		if _, ok := pkgs[n.Pkg]; !ok {
+			pkgs[n.Pkg] = make(map[string]int)
+		}
+	}
+	for id, deps := range d.graph {
+		n, ok := d.nodes[id]
+		if !ok {
+			continue
+		}
+		for dep := range deps {
+			if depNode, ok := d.nodes[dep]; ok && depNode.Pkg != n.Pkg {
+				pkgs[n.Pkg][depNode.Pkg]++
+			}
+		}
+	}
+	return pkgs
+}
+
+// ReachablePackages 返回 targetID 所在的包，以及 targetID 直接或间接依赖的声明所在的包，按包名排序
+func (d *DependencyInfo) ReachablePackages(targetID string) ([]string, error) {
+	deps, err := d.Traverse(targetID, DirectionForward, 0)
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]struct{}{d.nodes[targetID].Pkg: {}}
+	for _, id := range deps {
+		if n, ok := d.nodes[id]; ok {
+			set[n.Pkg] = struct{}{}
+		}
+	}
+	pkgs := make([]string, 0, len(set))
+	for pkg := range set {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs, nil
+}
+
+// sortedNodes 返回图中按名称排序的所有节点，包括只作为依赖出现的节点
+func sortedNodes(g Graph) []string {
+	set := make(map[string]struct{}, len(g))
+	for id, deps := range g {
+		set[id] = struct{}{}
+		for dep := range deps {
+			set[dep] = struct{}{}
+		}
+	}
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// WriteDOT 将依赖图以 Graphviz DOT 格式写入 w，边从依赖方指向被依赖方
+func WriteDOT(w io.Writer, g Graph) error {
+	lists := graphToLists(g)
+	nodes := sortedNodes(g)
+	var b strings.Builder
+	b.WriteString("digraph veronica {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, id := range nodes {
+		fmt.Fprintf(&b, "  %s;\n", dotQuote(id))
+	}
+	for _, id := range nodes {
+		for _, dep := range lists[id] {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(id), dotQuote(dep))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote 将标识符转换为 DOT 中带引号的字符串
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// WriteMermaid 将依赖图以 Mermaid flowchart 格式写入 w，边从依赖方指向被依赖方。
+// 节点的标识符中包含 Mermaid 不支持的字符，因此节点使用 n0、n1 等编号，完整的标识符作为节点的标签
+func WriteMermaid(w io.Writer, g Graph) error {
+	lists := graphToLists(g)
+	nodes := sortedNodes(g)
+	index := make(map[string]int, len(nodes))
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, id := range nodes {
+		index[id] = i
+		// 标签中的双引号需要使用 HTML 实体转义
+		fmt.Fprintf(&b, "  n%d[\"%s\"]\n", i, strings.ReplaceAll(id, `"`, "#quot;"))
+	}
+	for _, id := range nodes {
+		for _, dep := range lists[id] {
+			fmt.Fprintf(&b, "  n%d --> n%d\n", index[id], index[dep])
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON 将依赖图以邻接表的形式写入 w，key 为节点，value 为它依赖的节点列表
+func WriteJSON(w io.Writer, g Graph) error {
+	lists := graphToLists(g)
+	for _, id := range sortedNodes(g) {
+		if _, ok := lists[id]; !ok {
+			lists[id] = []string{}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(lists)
+}
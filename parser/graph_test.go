@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// newTestDependencyInfo 构建一个依赖关系为 a:A -> a:B -> b:C、a:A -> b:D 的依赖图
+func newTestDependencyInfo() *DependencyInfo {
+	d := &DependencyInfo{
+		nodes: map[string]*node{
+			"a:A": {Pkg: "a", Name: "A"},
+			"a:B": {Pkg: "a", Name: "B"},
+			"b:C": {Pkg: "b", Name: "C"},
+			"b:D": {Pkg: "b", Name: "D"},
+		},
+		graph:    make(Graph),
+		revGraph: make(Graph),
+	}
+	for _, edge := range [][2]string{{"a:A", "a:B"}, {"a:B", "b:C"}, {"a:A", "b:D"}} {
+		addDependency(d.graph, edge[0], edge[1])
+		addDependency(d.revGraph, edge[1], edge[0])
+	}
+	return d
+}
+
+func TestSubgraph(t *testing.T) {
+	d := newTestDependencyInfo()
+	tests := []struct {
+		name      string
+		roots     []string
+		direction Direction
+		depth     int
+		want      map[string][]string
+	}{
+		{
+			name: "all",
+			want: map[string][]string{"a:A": {"a:B", "b:D"}, "a:B": {"b:C"}, "b:C": {}, "b:D": {}},
+		},
+		{
+			name:      "forward-depth-1",
+			roots:     []string{"a:A"},
+			direction: DirectionForward,
+			depth:     1,
+			want:      map[string][]string{"a:A": {"a:B", "b:D"}, "a:B": {}, "b:D": {}},
+		},
+		{
+			name:      "reverse",
+			roots:     []string{"b:C"},
+			direction: DirectionReverse,
+			want:      map[string][]string{"a:A": {"a:B"}, "a:B": {"b:C"}, "b:C": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := d.Subgraph(tt.roots, tt.direction, tt.depth)
+			if err != nil {
+				t.Fatalf("Subgraph() error: %v", err)
+			}
+			if got := graphToLists(sub); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Subgraph() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := d.Subgraph([]string{"a:X"}, DirectionForward, 0); err == nil {
+		t.Errorf("expected error for undefined root")
+	}
+}
+
+func TestCollapsePackages(t *testing.T) {
+	d := newTestDependencyInfo()
+	got := graphToLists(d.CollapsePackages(d.graph))
+	want := map[string][]string{"a": {"b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CollapsePackages() = %v, want %v", got, want)
+	}
+}
+
+func TestPackageGraph(t *testing.T) {
+	d := newTestDependencyInfo()
+	// a:B -> b:C 和 a:A -> b:D 两条依赖关系
+	want := WeightedGraph{"a": {"b": 2}, "b": {}}
+	if got := d.PackageGraph(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PackageGraph() = %v, want %v", got, want)
+	}
+}
+
+func TestReachablePackages(t *testing.T) {
+	d := newTestDependencyInfo()
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{target: "a:A", want: []string{"a", "b"}},
+		{target: "a:B", want: []string{"a", "b"}},
+		{target: "b:C", want: []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := d.ReachablePackages(tt.target)
+			if err != nil {
+				t.Fatalf("ReachablePackages() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReachablePackages(%s) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	g := make(Graph)
+	addDependency(g, "a:(*T).M", "b:C")
+	tests := []struct {
+		name  string
+		write func(*bytes.Buffer) error
+		want  string
+	}{
+		{
+			name:  "dot",
+			write: func(b *bytes.Buffer) error { return WriteDOT(b, g) },
+			want: `digraph veronica {
+  rankdir=LR;
+  node [shape=box];
+  "a:(*T).M";
+  "b:C";
+  "a:(*T).M" -> "b:C";
+}
+`,
+		},
+		{
+			name:  "mermaid",
+			write: func(b *bytes.Buffer) error { return WriteMermaid(b, g) },
+			want: `flowchart LR
+  n0["a:(*T).M"]
+  n1["b:C"]
+  n0 --> n1
+`,
+		},
+		{
+			name:  "json",
+			write: func(b *bytes.Buffer) error { return WriteJSON(b, g) },
+			want: `{
+  "a:(*T).M": [
+    "b:C"
+  ],
+  "b:C": []
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tt.write(&b); err != nil {
+				t.Fatalf("write error: %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+package shop
+
+import "github.com/bootun/veronica/parser/material/bufutil"
+
+// OrderWriter makes shop import bufutil
+type OrderWriter = bufutil.BatchWriter[int]
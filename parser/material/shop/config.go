@@ -0,0 +1,32 @@
+package shop
+
+type Base struct {
+	ID int
+}
+
+type Config[T any] struct {
+	Base
+	Name    string
+	Timeout int
+	Extra   T
+}
+
+// UseName reads only the Name field
+func UseName(c *Config[string]) string {
+	return c.Name
+}
+
+// UseID reads the ID field promoted from the embedded Base
+func UseID(c Config[int]) int {
+	return c.ID
+}
+
+// NewConfig sets only the Timeout field
+func NewConfig() Config[int] {
+	return Config[int]{Timeout: 1}
+}
+
+// DefaultBase sets every field of Base without field names
+func DefaultBase() Base {
+	return Base{1}
+}
@@ -0,0 +1,42 @@
+package shop
+
+type Cart struct {
+	Items []int
+}
+
+func NewCart() *Cart {
+	return &Cart{}
+}
+
+func (c *Cart) Total() int {
+	sum := 0
+	for _, item := range c.Items {
+		sum += item
+	}
+	return sum
+}
+
+type Pricer interface {
+	Total() int
+}
+
+// Checkout calls Total directly
+func Checkout(c *Cart) int {
+	return c.Total()
+}
+
+// Charge calls Total through the Pricer interface
+func Charge(p Pricer) int {
+	return p.Total()
+}
+
+type Invoice struct{}
+
+func (Invoice) Total() int {
+	return 1
+}
+
+// Bill calls a method that has the same name as (*Cart).Total
+func Bill(i Invoice) int {
+	return i.Total()
+}
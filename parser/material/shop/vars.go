@@ -0,0 +1,30 @@
+package shop
+
+type Amount int
+
+// Limit refers to Amount only through its declared type
+const Limit Amount = 10
+
+// DefaultPricer has no initializer, only its declared type refers to Pricer
+var DefaultPricer Pricer
+
+// Price uses DefaultPricer and depends on Pricer through it
+func Price() int {
+	return DefaultPricer.Total()
+}
+
+type Kind int
+
+// KindB repeats the type and the iota expression of KindA implicitly
+const (
+	KindA Kind = iota
+	KindB
+)
+
+// KindName refers to Kind only through KindB
+func KindName(k int) string {
+	if k == int(KindB) {
+		return "b"
+	}
+	return "a"
+}
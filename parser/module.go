@@ -2,9 +2,11 @@ package parser
 
 import (
 	"os"
-	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
 )
 
 // Go mod 信息
@@ -12,7 +14,11 @@ type GoModuleInfo struct {
 	// module xxx
 	Name      string
 	GoVersion string
-	// ...
+	// Requires records the required modules, key: module path, value: version
+	Requires map[string]string
+	// Replaces records the replace directives, key: replaced module path(with version if specified),
+	// value: replacement path(with version if specified)
+	Replaces map[string]string
 }
 
 // ParseGoModuleInfo parse go.mod and return GoModuleInfo
@@ -25,21 +31,74 @@ func ParseGoModuleInfo(path string) (*GoModuleInfo, error) {
 }
 
 func parseGoModContent(content []byte) (*GoModuleInfo, error) {
-	var info GoModuleInfo
-	// TODO: simple parser
-	moduleExp := regexp.MustCompile(`module (.+)`)
-	goVersionExp := regexp.MustCompile(`go (.+)`)
-
-	if matches := moduleExp.FindSubmatch(content); len(matches) == 2 {
-		info.Name = string(matches[1])
-	} else {
+	f, err := modfile.Parse("go.mod", content, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to parse go.mod")
+	}
+	if f.Module == nil {
 		return nil, errors.New("module name not found")
 	}
-
-	if matches := goVersionExp.FindSubmatch(content); len(matches) == 2 {
-		info.GoVersion = string(matches[1])
-	} else {
+	if f.Go == nil {
 		return nil, errors.New("go version not found")
 	}
+	info := GoModuleInfo{
+		Name:      f.Module.Mod.Path,
+		GoVersion: f.Go.Version,
+		Requires:  make(map[string]string),
+		Replaces:  make(map[string]string),
+	}
+	for _, r := range f.Require {
+		info.Requires[r.Mod.Path] = r.Mod.Version
+	}
+	for _, r := range f.Replace {
+		info.Replaces[r.Old.String()] = r.New.String()
+	}
 	return &info, nil
 }
+
+// ModuleOf returns the required module that provides the package pkgPath,
+// or an empty string if pkgPath does not belong to any required module.
+func (m *GoModuleInfo) ModuleOf(pkgPath string) string {
+	var module string
+	for path := range m.Requires {
+		// 嵌套的模块(如 a/b 与 a/b/c)取最长的匹配
+		if (pkgPath == path || strings.HasPrefix(pkgPath, path+"/")) && len(path) > len(module) {
+			module = path
+		}
+	}
+	return module
+}
+
+// ChangedModules returns the paths of modules whose required version or
+// replacement differs between the two go.mod files, including added and removed modules.
+func ChangedModules(old, new *GoModuleInfo) []string {
+	changed := make(map[string]struct{})
+	for path, version := range old.Requires {
+		if v, ok := new.Requires[path]; !ok || v != version {
+			changed[path] = struct{}{}
+		}
+	}
+	for path := range new.Requires {
+		if _, ok := old.Requires[path]; !ok {
+			changed[path] = struct{}{}
+		}
+	}
+	replaced := func(replaces map[string]string, other map[string]string) {
+		for oldPath, newPath := range replaces {
+			if p, ok := other[oldPath]; !ok || p != newPath {
+				// 去掉可能存在的版本号，只保留模块路径
+				path, _, _ := strings.Cut(oldPath, "@")
+				changed[path] = struct{}{}
+			}
+		}
+	}
+	replaced(old.Replaces, new.Replaces)
+	replaced(new.Replaces, old.Replaces)
+
+	modules := make([]string, 0, len(changed))
+	for path := range changed {
+		modules = append(modules, path)
+	}
+	sort.Strings(modules)
+	return modules
+}
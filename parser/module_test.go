@@ -26,6 +26,36 @@ go 1.17`),
 			want: &GoModuleInfo{
 				Name:      "github.com/bootun/veronica",
 				GoVersion: "1.17",
+				Requires:  map[string]string{},
+				Replaces:  map[string]string{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "require and replace",
+			args: args{
+				gomod: []byte(`module github.com/bootun/veronica
+
+go 1.20
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/tools v0.24.0 // indirect
+)
+
+replace golang.org/x/tools => ../tools
+`),
+			},
+			want: &GoModuleInfo{
+				Name:      "github.com/bootun/veronica",
+				GoVersion: "1.20",
+				Requires: map[string]string{
+					"github.com/pkg/errors": "v0.9.1",
+					"golang.org/x/tools":    "v0.24.0",
+				},
+				Replaces: map[string]string{
+					"golang.org/x/tools": "../tools",
+				},
 			},
 			wantErr: false,
 		},
@@ -60,3 +90,57 @@ go 1.17`),
 		})
 	}
 }
+
+func TestChangedModules(t *testing.T) {
+	old := &GoModuleInfo{
+		Requires: map[string]string{
+			"github.com/pkg/errors":  "v0.9.1",
+			"github.com/spf13/cobra": "v1.8.0",
+			"gopkg.in/yaml.v2":       "v2.4.0",
+		},
+		Replaces: map[string]string{
+			"golang.org/x/tools": "golang.org/x/tools@v0.23.0",
+		},
+	}
+	new := &GoModuleInfo{
+		Requires: map[string]string{
+			"github.com/pkg/errors":  "v0.9.1",
+			"github.com/spf13/cobra": "v1.9.1",
+			"gopkg.in/yaml.v3":       "v3.0.1",
+		},
+		Replaces: map[string]string{
+			"golang.org/x/tools": "golang.org/x/tools@v0.24.0",
+		},
+	}
+	want := []string{"github.com/spf13/cobra", "golang.org/x/tools", "gopkg.in/yaml.v2", "gopkg.in/yaml.v3"}
+	if got := ChangedModules(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedModules() = %v, want %v", got, want)
+	}
+}
+
+func TestModuleOf(t *testing.T) {
+	info := &GoModuleInfo{
+		Requires: map[string]string{
+			"cloud.google.com/go":         "v0.110.0",
+			"cloud.google.com/go/storage": "v1.30.0",
+			"github.com/pkg/errors":       "v0.9.1",
+		},
+	}
+	tests := []struct {
+		pkgPath string
+		want    string
+	}{
+		{pkgPath: "github.com/pkg/errors", want: "github.com/pkg/errors"},
+		{pkgPath: "cloud.google.com/go/civil", want: "cloud.google.com/go"},
+		{pkgPath: "cloud.google.com/go/storage/internal", want: "cloud.google.com/go/storage"},
+		{pkgPath: "github.com/pkg/errorsx", want: ""},
+		{pkgPath: "context", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pkgPath, func(t *testing.T) {
+			if got := info.ModuleOf(tt.pkgPath); got != tt.want {
+				t.Errorf("ModuleOf(%s) = %s, want %s", tt.pkgPath, got, tt.want)
+			}
+		})
+	}
+}
@@ -36,7 +36,11 @@ func NewProject(root string) (*project, error) {
 	if cfg.GoMod == "" {
 		gomodPath = rootPath.Join("go.mod").String()
 	} else {
+		// relative path is relative to the project root
 		gomodPath = cfg.GoMod
+		if !path.New(gomodPath).IsAbs() {
+			gomodPath = rootPath.Join(gomodPath).String()
+		}
 	}
 	module, err := ParseGoModuleInfo(gomodPath)
 	if err != nil {
@@ -58,6 +62,7 @@ func NewProject(root string) (*project, error) {
 		if !strings.HasPrefix(entrypoint, moduleName) {
 			entrypoint = moduleName + "/" + entrypoint
 		}
+		entrypoint = normalizeEntrypoint(entrypoint)
 		fullRelPath := rootPath.Join(entrypoint)
 		relPath, err := fullRelPath.Rel(root)
 		if err != nil {
@@ -74,15 +79,33 @@ func NewProject(root string) (*project, error) {
 	}
 	// initialize project
 	project := &project{
-		directory: root,
-		Module:    module,
-		Services:  services,
-		Ignores:   ignores,
-		Hooks:     hooks,
+		directory:   root,
+		Module:      module,
+		Services:    services,
+		Ignores:     ignores,
+		Hooks:       hooks,
+		GlobalHooks: cfg.Hooks,
+		DiffMode:    cfg.Diff.Mode,
+		GoMod:       cfg.GoMod,
 	}
 	return project, nil
 }
 
+// normalizeEntrypoint removes the optional file part of entrypoint,
+// e.g. "example.com/cmd/api/main.go:Serve" becomes "example.com/cmd/api:Serve",
+// so that the entrypoint matches the object id of the declaration.
+func normalizeEntrypoint(entrypoint string) string {
+	pkg, name, ok := strings.Cut(entrypoint, ":")
+	if !ok || !strings.HasSuffix(pkg, ".go") {
+		return entrypoint
+	}
+	i := strings.LastIndex(pkg, "/")
+	if i < 0 {
+		return entrypoint
+	}
+	return GetObjectID(pkg[:i], pkg[i+1:], name)
+}
+
 // project represents a monolithic go project, every entrypoint is a service
 type project struct {
 	// Module records the information of go.mod
@@ -93,14 +116,58 @@ type project struct {
 	// key is entrypoint package name, value is match pattern
 	Ignores map[string][]string
 	Hooks   map[string][]string
+	// GlobalHooks is the top level hooks in veronica config,
+	// changes to files matching them affect all services
+	GlobalHooks []string
+	// DiffMode is the mode to compare declarations, see astdiff.Mode
+	DiffMode string
+	// GoMod is the go.mod option in veronica config, a relative path is relative
+	// to the project root, empty means go.mod in the project root
+	GoMod string
 
 	// root directory of project
 	directory string
 }
 
+// MatchGlobalHooks reports whether any of files matches the project's top level hooks,
+// and returns the first matched file.
+func (p *project) MatchGlobalHooks(files []string) (string, bool) {
+	for _, file := range files {
+		for _, pattern := range p.GlobalHooks {
+			if path.New(file).Match(pattern) {
+				return file, true
+			}
+		}
+	}
+	return "", false
+}
+
 type Service struct {
 	Name       string
 	Entrypoint string
 	Ignores    []string
 	Hooks      []string
 }
+
+// MatchHooks returns true if file matches any of the service's hook patterns.
+// file is a path relative to the project root, e.g. "deploy/api/Dockerfile".
+func (s Service) MatchHooks(file string) bool {
+	for _, pattern := range s.Hooks {
+		if path.New(file).Match(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchIgnores returns true if file matches any of the service's ignore patterns,
+// changes in such files do not affect the service.
+// file is a path relative to the project root, e.g. "pkg/store/doc.go".
+func (s Service) MatchIgnores(file string) bool {
+	for _, pattern := range s.Ignores {
+		if path.New(file).Match(pattern) {
+			return true
+		}
+	}
+	return false
+}
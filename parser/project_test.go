@@ -0,0 +1,75 @@
+package parser
+
+import "testing"
+
+func TestServiceMatchHooks(t *testing.T) {
+	svc := Service{
+		Name:  "api-gateway",
+		Hooks: []string{"**/Makefile", "go.mod", "deploy/api-gateway/**"},
+	}
+	tests := []struct {
+		name string
+		file string
+		want bool
+	}{
+		{name: "makefile-in-root", file: "Makefile", want: true},
+		{name: "makefile-in-subdir", file: "cmd/api-gateway/Makefile", want: true},
+		{name: "go-mod", file: "go.mod", want: true},
+		{name: "go-sum", file: "go.sum", want: false},
+		{name: "deploy-dir", file: "deploy/api-gateway/Dockerfile", want: true},
+		{name: "other-deploy-dir", file: "deploy/assets-manager/Dockerfile", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := svc.MatchHooks(tt.file); got != tt.want {
+				t.Errorf("MatchHooks(%s) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceMatchIgnores(t *testing.T) {
+	svc := Service{
+		Name:    "api-gateway",
+		Ignores: []string{"pkg/**/*doc.go", "**/*_mock.go"},
+	}
+	tests := []struct {
+		name string
+		file string
+		want bool
+	}{
+		{name: "doc", file: "pkg/store/doc.go", want: true},
+		{name: "nested-doc", file: "pkg/store/tag/tagdoc.go", want: true},
+		{name: "mock", file: "internal/repo/repo_mock.go", want: true},
+		{name: "source", file: "pkg/store/store.go", want: false},
+		{name: "doc-outside-pkg", file: "internal/doc.go", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := svc.MatchIgnores(tt.file); got != tt.want {
+				t.Errorf("MatchIgnores(%s) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEntrypoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		entrypoint string
+		want       string
+	}{
+		{name: "with-file", entrypoint: "example.com/cmd/api/main.go:Serve", want: "example.com/cmd/api:Serve"},
+		{name: "without-file", entrypoint: "example.com/cmd/api:Serve", want: "example.com/cmd/api:Serve"},
+		{name: "method", entrypoint: "example.com/internal/server/grpc.go:(*Server).Get", want: "example.com/internal/server:(*Server).Get"},
+		{name: "module-root", entrypoint: "example.com/main.go:main", want: "example.com:main"},
+		{name: "init", entrypoint: "example.com/cmd/api/main.go:init", want: "example.com/cmd/api/main.go:init"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeEntrypoint(tt.entrypoint); got != tt.want {
+				t.Errorf("normalizeEntrypoint(%s) = %s, want %s", tt.entrypoint, got, tt.want)
+			}
+		})
+	}
+}
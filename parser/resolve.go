@@ -0,0 +1,202 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSuggestions 是找不到 target 时最多给出的相似节点数量
+const maxSuggestions = 5
+
+// Resolve 将用户输入的 target 解析为依赖图中的节点，返回所有匹配的节点ID，按ID排序。
+// target 可以是以下几种形式：
+//   - 完整的节点ID，或者 entrypoint 的写法，如 example.com/cmd/api:Serve、example.com/cmd/api/main.go:Serve
+//   - 以包路径的后缀代替完整包路径，如 cmd/api:Serve、api:Serve
+//   - 包名加上标识符，如 api.Serve、store.(*Repo).Get
+//   - 只有标识符，如 Serve、(*Repo).Get，只有方法名时会匹配所有同名的方法
+//   - 声明所在的位置，如 main.go:12、cmd/api/main.go:12
+//
+// 返回多个节点时，说明 target 存在歧义。找不到任何节点时返回的错误中包含名称相近的节点
+func (d *DependencyInfo) Resolve(target string) ([]string, error) {
+	if target == "" {
+		return nil, fmt.Errorf("target is empty")
+	}
+	if _, ok := d.nodes[target]; ok {
+		return []string{target}, nil
+	}
+
+	var ids []string
+	if file, line, ok := parsePosition(target); ok {
+		ids = d.resolvePosition(file, line)
+	} else if pkg, name, ok := strings.Cut(normalizeEntrypoint(target), ":"); ok {
+		if _, ok := d.nodes[pkg+":"+name]; ok {
+			return []string{pkg + ":" + name}, nil
+		}
+		ids = d.match(pkg, name)
+	} else {
+		ids = d.match("", target)
+		// 包名与标识符之间以第一个 . 分隔，包路径中可能包含 .(如 example.com/cmd/api.Serve)，
+		// 因此从最后一个 / 之后开始查找
+		slash := strings.LastIndex(target, "/") + 1
+		if i := strings.Index(target[slash:], "."); i > 0 && !strings.HasPrefix(target[slash:], "(") {
+			ids = append(ids, d.match(target[:slash+i], target[slash+i+1:])...)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, d.notFound(target)
+	}
+	sort.Strings(ids)
+	return dedup(ids), nil
+}
+
+// parsePosition 解析形如 file.go:line 的位置
+func parsePosition(target string) (string, int, bool) {
+	file, lineStr, ok := strings.Cut(target, ":")
+	if !ok || !strings.HasSuffix(file, ".go") {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(lineStr)
+	if err != nil || line <= 0 {
+		return "", 0, false
+	}
+	return file, line, true
+}
+
+// resolvePosition 返回包含 file 第 line 行的声明，同时被多个声明包含时(如结构体和它的字段)返回范围最小的声明
+func (d *DependencyInfo) resolvePosition(file string, line int) []string {
+	dir, base := path.Split(file)
+	dir = strings.TrimSuffix(dir, "/")
+	var ids []string
+	smallest := -1
+	for id, n := range d.nodes {
+		if n.File != base || line < n.Line || line > n.EndLine {
+			continue
+		}
+		if dir != "" && !matchPackage(n.Pkg, dir) {
+			continue
+		}
+		switch size := n.EndLine - n.Line; {
+		case smallest < 0 || size < smallest:
+			smallest = size
+			ids = []string{id}
+		case size == smallest:
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// match 返回包路径以 pkg 结尾、名称为 name 的节点，pkg 为空时匹配所有的包。
+// name 只有方法名时，会匹配所有同名的方法
+func (d *DependencyInfo) match(pkg, name string) []string {
+	var ids []string
+	for id, n := range d.nodes {
+		if pkg != "" && !matchPackage(n.Pkg, pkg) {
+			continue
+		}
+		if n.Name == name || (strings.HasPrefix(n.Name, "(") && strings.HasSuffix(n.Name, ")."+name)) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// matchPackage 判断包路径 pkgPath 是否等于 suffix，或者以 /suffix 结尾
+func matchPackage(pkgPath, suffix string) bool {
+	return pkgPath == suffix || strings.HasSuffix(pkgPath, "/"+suffix)
+}
+
+// notFound 返回找不到 target 时的错误，错误中包含名称相近的节点
+func (d *DependencyInfo) notFound(target string) error {
+	// 只比较标识符的部分
+	name := target
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	} else if slash := strings.LastIndex(name, "/") + 1; !strings.HasPrefix(name[slash:], "(") {
+		if i := strings.Index(name[slash:], "."); i > 0 {
+			name = name[slash+i+1:]
+		}
+	}
+
+	type suggestion struct {
+		id       string
+		distance int
+	}
+	var suggestions []suggestion
+	maxDistance := len(name) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	for id, n := range d.nodes {
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(n.Name))
+		// 方法同时与不带接收器的方法名比较
+		if i := strings.LastIndex(n.Name, ")."); i >= 0 {
+			if d := levenshtein(strings.ToLower(name), strings.ToLower(n.Name[i+2:])); d < distance {
+				distance = d
+			}
+		}
+		if distance <= maxDistance {
+			suggestions = append(suggestions, suggestion{id: id, distance: distance})
+		}
+	}
+	if len(suggestions) == 0 {
+		return fmt.Errorf("target %s is not defined in project", target)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].id < suggestions[j].id
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "target %s is not defined in project, did you mean:", target)
+	for _, s := range suggestions {
+		fmt.Fprintf(&b, "\n  %s", s.id)
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// levenshtein 返回两个字符串之间的编辑距离
+func levenshtein(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	prev := make([]int, len(y)+1)
+	cur := make([]int, len(y)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		cur[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(y)]
+}
+
+// dedup 去除已排序的 ids 中重复的元素
+func dedup(ids []string) []string {
+	result := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
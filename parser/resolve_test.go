@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	pkgs, err := LoadPackages("./material")
+	if err != nil {
+		t.Fatalf("failed to load packages: %v", err)
+	}
+	depInfo, err := BuildDependency(pkgs)
+	if err != nil {
+		t.Fatalf("failed to build dependency: %v", err)
+	}
+	const pkg = "github.com/bootun/veronica/parser/material/shop"
+	tests := []struct {
+		name   string
+		target string
+		want   []string
+	}{
+		{name: "id", target: pkg + ":NewCart", want: []string{pkg + ":NewCart"}},
+		{name: "entrypoint-with-file", target: pkg + "/shop.go:NewCart", want: []string{pkg + ":NewCart"}},
+		{name: "package-suffix", target: "material/shop:NewCart", want: []string{pkg + ":NewCart"}},
+		{name: "package-name", target: "shop.NewCart", want: []string{pkg + ":NewCart"}},
+		{name: "package-name-method", target: "shop.(*Cart).Total", want: []string{pkg + ":(*Cart).Total"}},
+		{name: "name", target: "NewCart", want: []string{pkg + ":NewCart"}},
+		{name: "method", target: "(*Cart).Total", want: []string{pkg + ":(*Cart).Total"}},
+		{name: "method-name", target: "Total", want: []string{pkg + ":(*Cart).Total", pkg + ":(Invoice).Total"}},
+		{name: "field", target: "Config.Name", want: []string{pkg + ":Config.Name"}},
+		{name: "position", target: "config.go:16", want: []string{pkg + ":UseName"}},
+		{name: "position-field", target: "material/shop/config.go:9", want: []string{pkg + ":Config.Name"}},
+		{name: "position-type", target: "config.go:7", want: []string{pkg + ":Config"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := depInfo.Resolve(tt.target)
+			if err != nil {
+				t.Fatalf("Resolve(%s) error: %v", tt.target, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%s) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := depInfo.Resolve(""); err == nil || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("Resolve(\"\") error = %v, want an error without suggestions", err)
+	}
+	_, err = depInfo.Resolve("shop.NewCrat")
+	if err == nil || !strings.Contains(err.Error(), pkg+":NewCart") {
+		t.Errorf("Resolve() error = %v, want suggestion %s", err, pkg+":NewCart")
+	}
+	_, err = depInfo.Resolve("Totl")
+	if err == nil || !strings.Contains(err.Error(), pkg+":(*Cart).Total") {
+		t.Errorf("Resolve() error = %v, want suggestion %s", err, pkg+":(*Cart).Total")
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "abc", want: 3},
+		{a: "kitten", b: "sitting", want: 3},
+		{a: "GetTag", b: "GetTag", want: 0},
+		{a: "NewCrat", b: "NewCart", want: 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}